@@ -5,8 +5,15 @@ import (
 	"SkinRest/internal/api"
 	"SkinRest/internal/database"
 	"SkinRest/internal/middleware"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,7 +24,11 @@ func main() {
 
 	db := database.New() // initialize database
 
-	r := api.NewRouter(logger, db) // initialize new router
+	// stop background work and the server on Ctrl+C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	r := api.NewRouter(ctx, logger, db) // initialize new router
 
 	var addr string
 	if cfg.Server.Host == "localhost" {
@@ -32,7 +43,20 @@ func main() {
 
 	logger.Info("Starting on " + addr)
 	logger.Sugar().Infof("Developed with Gin Framework version: %s", gin.Version)
-	if err := r.Run(addr); err != nil { // run server
+	srv := &http.Server{Addr: addr, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) { // run server
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Info("Shutting down")
+
+	// let requests in flight finish
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatal(err)
 	}
 }
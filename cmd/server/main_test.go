@@ -6,9 +6,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"image"
+	"image/color"
+	"image/png"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -32,6 +37,12 @@ type AboutMeResponse struct {
 	Skins []skin
 }
 
+type SkinsPageResponse struct {
+	Skins      []skin `json:"skins"`
+	NextCursor string `json:"next_cursor"`
+	Total      int    `json:"total"`
+}
+
 type RemoveSkinResponse struct {
 	Status string `json:"status"`
 }
@@ -43,6 +54,15 @@ type skin struct {
 	Src  string `json:"Src"`
 }
 
+// storedSkin is a skin together with its stored texture and version
+type storedSkin struct {
+	Id      int    `json:"Id"`
+	Name    string `json:"Name"`
+	Type    string `json:"Type"`
+	Texture string `json:"Texture"`
+	Version int    `json:"Version"`
+}
+
 const (
 	addr             string = "http://localhost:8081/api/v1"
 	TestUserLogin    string = "User2347"
@@ -106,7 +126,7 @@ func TestApi(t *testing.T) {
 
 	expected = &AboutMeResponse{
 		Login: TestUserLogin,
-		Skins: []skin{{Id: id, Name: "MySkin", Type: "Classic", Src: "Aid"}},
+		Skins: []skin{{Id: id, Name: "MySkin", Type: "Classic"}},
 	}
 	err = aboutMeTest(t, token, expected)
 	if err != nil {
@@ -121,6 +141,34 @@ func TestApi(t *testing.T) {
 	} else {
 		fmt.Println("Succesful test #9")
 	}
+
+	err = registerAfterRenameTest(t)
+	if err != nil {
+		fmt.Printf("Error test #10: %v", err)
+	} else {
+		fmt.Println("Succesful test #10")
+	}
+
+	err = skinIfMatchTest(t, token)
+	if err != nil {
+		fmt.Printf("Error test #11: %v", err)
+	} else {
+		fmt.Println("Succesful test #11")
+	}
+
+	err = skinVersionsTest(t, token)
+	if err != nil {
+		fmt.Printf("Error test #12: %v", err)
+	} else {
+		fmt.Println("Succesful test #12")
+	}
+
+	err = activeSkinTest(t, token)
+	if err != nil {
+		fmt.Printf("Error test #13: %v", err)
+	} else {
+		fmt.Println("Succesful test #13")
+	}
 }
 
 func healthCheckTest(t *testing.T) error {
@@ -252,29 +300,75 @@ func aboutMeTest(t *testing.T, token string, expected *AboutMeResponse) error {
 
 }
 
+// skinPNG is a 64x64 classic skin texture, shade tells textures apart
+func skinPNG(shade uint8) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: shade, G: uint8(x * 4), B: uint8(y * 4), A: 255})
+		}
+	}
+	// an opaque image would be encoded without its alpha channel, which skins need
+	img.SetNRGBA(0, 0, color.NRGBA{})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		log.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// skinUploadRequest builds a multipart skin request carrying fields and the
+// texture, so the test does not depend on Mojang being reachable
+func skinUploadRequest(method, url, token string, fields map[string]string, texture []byte) (*http.Request, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := form.CreateFormFile("skinfile", "skin.png")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(texture); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, url, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Add(AuthHeader, "Bearer "+token)
+	return req, nil
+}
+
 func addSkinTest(t *testing.T, token string) (int, error) {
 
 	expected := &skin{
 		Name: "MySkin",
 		Type: "Classic",
-		Src:  "Aid",
 	}
 	expectedCode := 201
 
-	body := gin.H{
+	fields := map[string]string{
 		"skinname": "MySkin",
 		"skintype": "Classic",
-		"skinsrc":  "Aid",
 	}
-	jsonBody, _ := json.Marshal(body)
 
 	client := &http.Client{}
-	req, err := http.NewRequest(http.MethodPost, addr+"/skins/add", bytes.NewBuffer(jsonBody))
+	req, err := skinUploadRequest(http.MethodPost, addr+"/skins/add", token, fields, skinPNG(1))
 	if err != nil {
 		log.Fatal(err)
 		return 0, err
 	}
-	req.Header.Add(AuthHeader, "Bearer "+token)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -305,7 +399,6 @@ func getSkinsTest(t *testing.T, token string, skinId int) error {
 			Id:   skinId,
 			Name: "MySkin",
 			Type: "Classic",
-			Src:  "Aid",
 		},
 	}
 	expectedCode := 200
@@ -327,13 +420,15 @@ func getSkinsTest(t *testing.T, token string, skinId int) error {
 
 	assert.Equal(t, expectedCode, resp.StatusCode)
 
-	var skinsResp []skin
+	var skinsResp *SkinsPageResponse
 	if err := json.NewDecoder(resp.Body).Decode(&skinsResp); err != nil {
 		fmt.Printf("Error decoding GetSkins response: %v", err)
 		return err
 	}
 
-	assert.Equal(t, expected, skinsResp)
+	assert.Equal(t, expected, skinsResp.Skins)
+	assert.Equal(t, 1, skinsResp.Total)
+	assert.Empty(t, skinsResp.NextCursor)
 
 	return nil
 }
@@ -344,7 +439,6 @@ func GetSkinTest(t *testing.T, token string, skinId int) error {
 		Id:   skinId,
 		Name: "MySkin",
 		Type: "Classic",
-		Src:  "Aid",
 	}
 	expectedCode := 200
 
@@ -409,3 +503,368 @@ func RemoveSkinTest(t *testing.T, token string, skinId int) error {
 	assert.Equal(t, expected, skinResp)
 	return nil
 }
+
+// registerAfterRenameTest registers a login that another user renamed away
+// from, that user keeps the offline UUID of the login
+func registerAfterRenameTest(t *testing.T) error {
+	suffix := time.Now().UnixNano() % 1e8
+	oldLogin := fmt.Sprintf("Old%d", suffix)
+	newLogin := fmt.Sprintf("New%d", suffix)
+
+	register := func(login string) (int, error) {
+		jsonBody, _ := json.Marshal(gin.H{"login": login, "password": TestUserPassword})
+		resp, err := http.Post(addr+"/user/register", "application/json", bytes.NewBuffer(jsonBody))
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	code, err := register(oldLogin)
+	if err != nil {
+		return err
+	}
+	assert.Equal(t, 200, code)
+
+	jsonBody, _ := json.Marshal(gin.H{"login": oldLogin, "password": TestUserPassword})
+	resp, err := http.Post(addr+"/user/login", "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var loginResp *LoginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&loginResp); err != nil {
+		return err
+	}
+
+	jsonBody, _ = json.Marshal(gin.H{"login": newLogin})
+	req, err := http.NewRequest(http.MethodPut, addr+"/user/me/login", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Add(AuthHeader, "Bearer "+loginResp.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	renameResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer renameResp.Body.Close()
+	assert.Equal(t, 200, renameResp.StatusCode)
+
+	// the old login is free again
+	code, err = register(oldLogin)
+	if err != nil {
+		return err
+	}
+	assert.Equal(t, 200, code)
+
+	return nil
+}
+
+// jsonRequest builds an authenticated request with body encoded as JSON, if not nil
+func jsonRequest(method, url, token string, body any) (*http.Request, error) {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, url, &buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add(AuthHeader, "Bearer "+token)
+	return req, nil
+}
+
+// addStoredSkin uploads a fixture texture as a new skin
+func addStoredSkin(t *testing.T, token, name string, shade uint8) (*storedSkin, error) {
+	req, err := skinUploadRequest(http.MethodPost, addr+"/skins/add", token, map[string]string{"skinname": name}, skinPNG(shade))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, 201, resp.StatusCode)
+
+	var added *storedSkin
+	if err := json.NewDecoder(resp.Body).Decode(&added); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// skinIfMatchTest renames a skin with PATCH, which keeps its texture and
+// type, then retries PATCH and PUT with stale or weak If-Match tags
+func skinIfMatchTest(t *testing.T, token string) error {
+	added, err := addStoredSkin(t, token, "Versioned", 2)
+	if err != nil {
+		return err
+	}
+	skinUrl := fmt.Sprintf("%s/skins/%d", addr, added.Id)
+
+	req, err := jsonRequest(http.MethodPatch, skinUrl, token, gin.H{"skinname": "Renamed"})
+	if err != nil {
+		return err
+	}
+	req.Header.Set("If-Match", fmt.Sprintf(`"v%d"`, added.Version))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, fmt.Sprintf(`"v%d"`, added.Version+1), resp.Header.Get("ETag"))
+
+	var patched *storedSkin
+	if err := json.NewDecoder(resp.Body).Decode(&patched); err != nil {
+		return err
+	}
+	assert.Equal(t, "Renamed", patched.Name)
+	assert.Equal(t, added.Type, patched.Type)
+	assert.Equal(t, added.Texture, patched.Texture)
+
+	// the version read before the rename is stale now
+	req, err = jsonRequest(http.MethodPatch, skinUrl, token, gin.H{"skinname": "Stale"})
+	if err != nil {
+		return err
+	}
+	req.Header.Set("If-Match", fmt.Sprintf(`"v%d"`, added.Version))
+
+	staleResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer staleResp.Body.Close()
+	assert.Equal(t, 412, staleResp.StatusCode)
+
+	// If-Match is a strong comparison, the current version as a weak tag fails
+	req, err = jsonRequest(http.MethodPut, skinUrl, token, gin.H{"skinname": "Weak", "skintype": "Classic"})
+	if err != nil {
+		return err
+	}
+	req.Header.Set("If-Match", fmt.Sprintf(`W/"v%d"`, patched.Version))
+
+	weakResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer weakResp.Body.Close()
+	assert.Equal(t, 412, weakResp.StatusCode)
+
+	return deleteSkin(t, token, added.Id)
+}
+
+// skinVersionsTest replaces the texture of a skin, lists its versions and
+// restores the first one, which comes back as a new version
+func skinVersionsTest(t *testing.T, token string) error {
+	added, err := addStoredSkin(t, token, "Restored", 3)
+	if err != nil {
+		return err
+	}
+	skinUrl := fmt.Sprintf("%s/skins/%d", addr, added.Id)
+
+	req, err := skinUploadRequest(http.MethodPut, skinUrl, token, map[string]string{"skinname": "Restored"}, skinPNG(4))
+	if err != nil {
+		return err
+	}
+
+	replaceResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer replaceResp.Body.Close()
+	assert.Equal(t, 200, replaceResp.StatusCode)
+
+	var replaced *storedSkin
+	if err := json.NewDecoder(replaceResp.Body).Decode(&replaced); err != nil {
+		return err
+	}
+	assert.NotEqual(t, added.Texture, replaced.Texture)
+
+	req, err = jsonRequest(http.MethodGet, skinUrl+"/versions", token, nil)
+	if err != nil {
+		return err
+	}
+
+	versionsResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer versionsResp.Body.Close()
+	assert.Equal(t, 200, versionsResp.StatusCode)
+
+	var versions []struct {
+		Version int    `json:"Version"`
+		Texture string `json:"Texture"`
+	}
+	if err := json.NewDecoder(versionsResp.Body).Decode(&versions); err != nil {
+		return err
+	}
+	if assert.Len(t, versions, 2) {
+		assert.Equal(t, replaced.Version, versions[0].Version)
+		assert.Equal(t, added.Texture, versions[1].Texture)
+	}
+
+	req, err = jsonRequest(http.MethodPost, fmt.Sprintf("%s/versions/%d/restore", skinUrl, added.Version), token, nil)
+	if err != nil {
+		return err
+	}
+
+	restoreResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer restoreResp.Body.Close()
+	assert.Equal(t, 200, restoreResp.StatusCode)
+
+	var restored *storedSkin
+	if err := json.NewDecoder(restoreResp.Body).Decode(&restored); err != nil {
+		return err
+	}
+	assert.Equal(t, replaced.Version+1, restored.Version)
+	assert.Equal(t, added.Texture, restored.Texture)
+
+	// a version the skin never had
+	req, err = jsonRequest(http.MethodPost, skinUrl+"/versions/99/restore", token, nil)
+	if err != nil {
+		return err
+	}
+
+	missingResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer missingResp.Body.Close()
+	assert.Equal(t, 404, missingResp.StatusCode)
+
+	return deleteSkin(t, token, added.Id)
+}
+
+// activeSkinTest selects a skin, follows the public endpoint to its texture,
+// refuses another user's skin and takes the skin off again
+func activeSkinTest(t *testing.T, token string) error {
+	added, err := addStoredSkin(t, token, "Worn", 5)
+	if err != nil {
+		return err
+	}
+
+	setActive := func(token string, id int) (int, error) {
+		req, err := jsonRequest(http.MethodPut, addr+"/user/me/active-skin", token, gin.H{"skinid": id})
+		if err != nil {
+			return 0, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	// the public endpoint is fetched without following its redirect
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	publicSkin := func() (*http.Response, error) {
+		return noRedirect.Get(addr + "/users/" + TestUserLogin + "/skin")
+	}
+
+	code, err := setActive(token, added.Id)
+	if err != nil {
+		return err
+	}
+	assert.Equal(t, 200, code)
+
+	// a stored texture is redirected to, skins without one are proxied from their source
+	resp, err := publicSkin()
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, 302, resp.StatusCode)
+	assert.Equal(t, added.Texture, resp.Header.Get("Location"))
+
+	// the skin of another user cannot be selected
+	otherLogin := fmt.Sprintf("Other%d", time.Now().UnixNano()%1e8)
+	jsonBody, _ := json.Marshal(gin.H{"login": otherLogin, "password": TestUserPassword})
+	registerResp, err := http.Post(addr+"/user/register", "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+	defer registerResp.Body.Close()
+	assert.Equal(t, 200, registerResp.StatusCode)
+
+	loginResp, err := http.Post(addr+"/user/login", "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+	defer loginResp.Body.Close()
+
+	var otherLoginResp *LoginResponse
+	if err := json.NewDecoder(loginResp.Body).Decode(&otherLoginResp); err != nil {
+		return err
+	}
+
+	othersSkin, err := addStoredSkin(t, otherLoginResp.Token, "NotYours", 6)
+	if err != nil {
+		return err
+	}
+
+	code, err = setActive(token, othersSkin.Id)
+	if err != nil {
+		return err
+	}
+	assert.Equal(t, 404, code)
+
+	// taking the skin off leaves the public endpoint with nothing to serve
+	req, err := jsonRequest(http.MethodDelete, addr+"/user/me/active-skin", token, nil)
+	if err != nil {
+		return err
+	}
+	clearResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer clearResp.Body.Close()
+	assert.Equal(t, 200, clearResp.StatusCode)
+
+	clearedResp, err := publicSkin()
+	if err != nil {
+		return err
+	}
+	defer clearedResp.Body.Close()
+	assert.Equal(t, 404, clearedResp.StatusCode)
+
+	if err := deleteSkin(t, otherLoginResp.Token, othersSkin.Id); err != nil {
+		return err
+	}
+	return deleteSkin(t, token, added.Id)
+}
+
+// deleteSkin removes a skin a test added
+func deleteSkin(t *testing.T, token string, id int) error {
+	req, err := jsonRequest(http.MethodDelete, fmt.Sprintf("%s/skins/%d", addr, id), token, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+
+	return nil
+}
@@ -2,14 +2,21 @@ package config
 
 import (
 	"log"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Auth     AuthConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Auth      AuthConfig
+	Storage   StorageConfig
+	Render    RenderConfig
+	Yggdrasil YggdrasilConfig
+	Mojang    MojangConfig
+	Fetch     FetchConfig
+	Sync      SyncConfig
 }
 
 type ServerConfig struct {
@@ -17,6 +24,9 @@ type ServerConfig struct {
 	Port    int    `envconfig:"SERVER_PORT" default:"8081"`
 	ApiEnv  string `envconfig:"API_ENV" default:"local"`
 	GinMode string `envconfig:"GIN_MODE" default:"debug"`
+
+	// PublicURL is the externally reachable address used to build texture links
+	PublicURL string `envconfig:"SERVER_PUBLIC_URL" default:"http://localhost:8081"`
 }
 
 type DatabaseConfig struct {
@@ -31,6 +41,54 @@ type DatabaseConfig struct {
 
 type AuthConfig struct {
 	JwtSecret string `envconfig:"AUTH_JWT_SECRET" required:"true"`
+
+	AccessTokenTTL  time.Duration `envconfig:"AUTH_ACCESS_TOKEN_TTL" default:"15m"`
+	RefreshTokenTTL time.Duration `envconfig:"AUTH_REFRESH_TOKEN_TTL" default:"720h"` // a session ends when it goes unused this long
+
+	// BootstrapToken is a one-time secret that makes its first user admin while no admin exists
+	BootstrapToken string `envconfig:"AUTH_BOOTSTRAP_TOKEN"`
+}
+
+type StorageConfig struct {
+	Driver   string `envconfig:"STORAGE_DRIVER" default:"local"` // "local" or "s3"
+	LocalDir string `envconfig:"STORAGE_LOCAL_DIR"`              // defaults to ./textures or /root/textures depending on API_ENV
+
+	S3Endpoint  string `envconfig:"STORAGE_S3_ENDPOINT"` // e.g. "http://localhost:9000" for MinIO
+	S3Region    string `envconfig:"STORAGE_S3_REGION" default:"us-east-1"`
+	S3Bucket    string `envconfig:"STORAGE_S3_BUCKET"`
+	S3AccessKey string `envconfig:"STORAGE_S3_ACCESS_KEY"`
+	S3SecretKey string `envconfig:"STORAGE_S3_SECRET_KEY"`
+}
+
+type RenderConfig struct {
+	CacheEntries int    `envconfig:"RENDER_CACHE_ENTRIES" default:"1024"` // in-memory LRU capacity
+	CacheDir     string `envconfig:"RENDER_CACHE_DIR"`                    // optional on-disk tier, disabled when empty
+
+	// SourceHashTTL is how long the texture hash of a skin without a stored texture is trusted, 0 disables it
+	SourceHashTTL time.Duration `envconfig:"RENDER_SOURCE_HASH_TTL" default:"10m"`
+}
+
+type YggdrasilConfig struct {
+	ServerName string `envconfig:"YGGDRASIL_SERVER_NAME" default:"SkinRest"`
+	KeyPath    string `envconfig:"YGGDRASIL_KEY_PATH"` // RSA key for signing textures, generated if missing; defaults to ./keys or /root/keys depending on API_ENV
+}
+
+type MojangConfig struct {
+	APIURL     string        `envconfig:"MOJANG_API_URL" default:"https://api.mojang.com"`
+	SessionURL string        `envconfig:"MOJANG_SESSION_URL" default:"https://sessionserver.mojang.com"`
+	Timeout    time.Duration `envconfig:"MOJANG_TIMEOUT" default:"10s"`
+}
+
+// FetchConfig limits downloads of URL skin sources
+type FetchConfig struct {
+	Timeout      time.Duration `envconfig:"FETCH_TIMEOUT" default:"10s"`
+	MaxRedirects int           `envconfig:"FETCH_MAX_REDIRECTS" default:"3"`
+	AllowPrivate bool          `envconfig:"FETCH_ALLOW_PRIVATE" default:"false"` // allow private, loopback and link-local addresses
+}
+
+type SyncConfig struct {
+	Interval time.Duration `envconfig:"SYNC_INTERVAL" default:"6h"` // re-sync of nickname-sourced skins, 0 disables it
+	Delay    time.Duration `envconfig:"SYNC_DELAY" default:"1s"`    // pause between the Mojang lookups of a run
 }
 
 func GetConfig() *Config {
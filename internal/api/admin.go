@@ -0,0 +1,133 @@
+package api
+
+import (
+	"SkinRest/config"
+	"SkinRest/internal/database"
+	"SkinRest/pkg/models"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetUserRole godoc
+// @Summary Change a user's role
+// @Description Gives a user the user, moderator or admin role. Requires the admin role and a logged in session, API keys are refused.
+// @Description The last admin cannot be demoted.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param login path string true "User login"
+// @Param role body models.NewRole true "New role"
+// @Success 200 {object} models.UserRole
+// @Failure 400 {object} gin.H {"error": "Missing or invalid fields"}
+// @Failure 403 {object} gin.H {"error": "Your role does not allow this request"}
+// @Failure 404 {object} gin.H {"error": "This user does not exist"}
+// @Failure 409 {object} gin.H {"error": "The last admin cannot be demoted"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /admin/users/{login}/role [put]
+func SetUserRole(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	var newRole models.NewRole
+
+	// Get JSON Body
+	if err := c.ShouldBindJSON(&newRole); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid fields: " + err.Error()})
+		return
+	}
+
+	userData, err := appctx.SetUserRole(c.Param("login"), newRole.Role)
+	if err != nil {
+		switch err {
+		case models.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case models.ErrLastAdmin:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			appctx.Logger.Error(err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.UserRole{Login: userData.Login, Role: userData.Role})
+}
+
+// bootstrap tokens shorter than this are refused at startup
+const minBootstrapTokenLength int = 16
+
+// BootstrapAdmin godoc
+// @Summary Become the first admin
+// @Description Makes the logged in user admin with the one-time token set in AUTH_BOOTSTRAP_TOKEN.
+// @Description Only works while the server has no admin, further admins are appointed with /admin/users/{login}/role.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param token body models.BootstrapRequest true "Bootstrap token"
+// @Success 200 {object} models.UserRole
+// @Failure 400 {object} gin.H {"error": "Missing or invalid fields"}
+// @Failure 403 {object} gin.H {"error": "Invalid bootstrap token"}
+// @Failure 404 {object} gin.H {"error": "Admin bootstrap is not enabled on this server"}
+// @Failure 409 {object} gin.H {"error": "An admin already exists, ask them for a role"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /admin/bootstrap [post]
+func BootstrapAdmin(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	bootstrapToken := config.GetConfig().Auth.BootstrapToken
+	if bootstrapToken == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": models.ErrBootstrapDisabled.Error()})
+		return
+	}
+
+	var request models.BootstrapRequest
+
+	// Get JSON Body
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid fields: " + err.Error()})
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(request.Token), []byte(bootstrapToken)) != 1 {
+		appctx.Logger.Warn("bootstrap admin: wrong token presented by " + userdata.Login)
+		c.JSON(http.StatusForbidden, gin.H{"error": models.ErrInvalidBootstrapToken.Error()})
+		return
+	}
+
+	if err := appctx.BootstrapAdmin(userdata); err != nil {
+		switch err {
+		case models.ErrAdminExists:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case models.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			appctx.Logger.Error(err.Error())
+		}
+		return
+	}
+
+	appctx.Logger.Info("bootstrap admin: granted admin role to " + userdata.Login)
+
+	c.JSON(http.StatusOK, models.UserRole{Login: userdata.Login, Role: models.RoleAdmin})
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"SkinRest/internal/database"
+	"SkinRest/pkg/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateApiKey godoc
+// @Summary Create a personal API key
+// @Description Creates a long-lived key for scripts, limited to the given scopes. The key is only returned in this response.
+// @Description Send it in the X-Api-Key header or as a Bearer token.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param key body models.NewApiKey true "Key name and scopes"
+// @Success 201 {object} models.ApiKeyCreated
+// @Failure 400 {object} gin.H {"error": "Missing or invalid fields"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /user/api-keys [post]
+func CreateApiKey(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	var apiKey models.NewApiKey
+
+	// Get JSON Body
+	if err := c.ShouldBindJSON(&apiKey); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid fields: " + err.Error()})
+		return
+	}
+
+	created, err := appctx.CreateApiKey(userdata, &apiKey)
+	if err != nil {
+		if err == models.ErrTooManyApiKeys {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetApiKeys godoc
+// @Summary List personal API keys
+// @Description Lists the user's API keys, without the keys themselves
+// @Tags user
+// @Produce json
+// @Success 200 {array} models.ApiKey
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /user/api-keys [get]
+func GetApiKeys(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	apiKeys, err := appctx.GetUserApiKeys(userdata)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeys)
+}
+
+// DeleteApiKey godoc
+// @Summary Revoke a personal API key
+// @Description Deletes one of the user's API keys, requests made with it are refused from then on
+// @Tags user
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} gin.H {"status": "Success"}
+// @Failure 400 {object} gin.H {"error": "Error message"}
+// @Failure 404 {object} gin.H {"error": "This API key does not exist"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /user/api-keys/{id} [delete]
+func DeleteApiKey(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get key id from path
+	id, err := idParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := appctx.DeleteUserApiKey(userdata, id); err != nil {
+		if err == models.ErrApiKeyNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Success"})
+}
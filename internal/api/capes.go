@@ -0,0 +1,311 @@
+package api
+
+import (
+	"SkinRest/internal/database"
+	"SkinRest/internal/texture"
+	"SkinRest/pkg/models"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	maxCapeNameLength int = 30
+
+	capeFileField string = "capefile" // multipart field carrying the PNG texture
+)
+
+// AddNewCape godoc
+// @Summary Add a new cape
+// @Description Uploads a 64x32 PNG cape for the authenticated user, returning the created cape data.
+// @Description With elytra=true the texture must also carry an elytra.
+// @Tags capes
+// @Accept mpfd
+// @Produce json
+// @Param capename formData string true "Cape name"
+// @Param capefile formData file true "Cape texture (64x32 RGBA PNG)"
+// @Param elytra formData bool false "Require a non-empty elytra region"
+// @Success 201 {object} models.CapeData
+// @Failure 400 {object} gin.H {"error": "Missing or invalid fields"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /capes/add [post]
+func AddNewCape(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	var cape models.Cape
+
+	// Get form fields
+	if err := c.ShouldBindWith(&cape, binding.FormMultipart); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid fields: " + err.Error()})
+		return
+	}
+
+	if len(cape.Name) > maxCapeNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("cape name must not exceed %d characters", maxCapeNameLength),
+		})
+		return
+	}
+
+	// Get uploaded texture
+	data, err := readTextureFile(c, capeFileField)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if data == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrCapeFileMissing.Error()})
+		return
+	}
+
+	img, err := texture.DecodeCape(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if cape.Elytra && !texture.HasElytra(img) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrCapeElytraEmpty.Error()})
+		return
+	}
+
+	// Save cape to database
+	capeData, err := appctx.AddNewCape(userdata, &cape, data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, capeData)
+}
+
+// GetCapesCollection godoc
+// @Summary Get user capes collection
+// @Description Retrieves all capes of the authenticated user
+// @Tags capes
+// @Produce json
+// @Success 200 {array} models.CapeData
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /capes [get]
+func GetCapesCollection(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user capes collection from database
+	capes, err := appctx.GetUserCapes(userdata)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, capes)
+}
+
+// GetCape godoc
+// @Summary Get cape information
+// @Description Retrieves one of the authenticated user's capes
+// @Tags capes
+// @Produce json
+// @Param id path int true "Cape ID"
+// @Success 200 {object} models.CapeData
+// @Failure 400 {object} gin.H {"error": "Error message"}
+// @Failure 404 {object} gin.H {"error": "This cape does not exist"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /capes/{id} [get]
+func GetCape(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get cape id from path
+	id, err := idParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	capeData, err := appctx.GetUserCape(userdata, id)
+	if err != nil {
+		if err == models.ErrCapeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, capeData)
+}
+
+// DeleteCape godoc
+// @Summary Delete a cape
+// @Description Deletes one of the authenticated user's capes, taking it off if it is worn
+// @Tags capes
+// @Produce json
+// @Param id path int true "Cape ID"
+// @Success 200 {object} gin.H {"status": "Success"}
+// @Failure 400 {object} gin.H {"error": "Error message"}
+// @Failure 404 {object} gin.H {"error": "This cape does not exist"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /capes/{id} [delete]
+func DeleteCape(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get cape id from path
+	id, err := idParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Remove cape from database
+	if err := appctx.DeleteUserCape(userdata, id); err != nil {
+		if err == models.ErrCapeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Success"})
+}
+
+// SetActiveCape godoc
+// @Summary Select the active cape
+// @Description Selects which of the user's capes is worn in game
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param cape body models.ActiveCape true "Cape to wear"
+// @Success 200 {object} models.CapeData
+// @Failure 400 {object} gin.H {"error": "Missing or invalid fields"}
+// @Failure 404 {object} gin.H {"error": "This cape does not exist"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /user/me/active-cape [put]
+func SetActiveCape(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	var activeCape models.ActiveCape
+
+	// Get JSON Body
+	if err := c.ShouldBindJSON(&activeCape); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid fields: " + err.Error()})
+		return
+	}
+
+	capeData, err := appctx.SetActiveCape(userdata, activeCape.CapeId)
+	if err != nil {
+		if err == models.ErrCapeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, capeData)
+}
+
+// ClearActiveCape godoc
+// @Summary Take the active cape off
+// @Description Leaves the user without a cape in game
+// @Tags user
+// @Produce json
+// @Success 200 {object} gin.H {"status": "Success"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /user/me/active-cape [delete]
+func ClearActiveCape(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if err := appctx.ClearActiveCape(userdata); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Success"})
+}
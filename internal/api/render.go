@@ -0,0 +1,274 @@
+package api
+
+import (
+	"SkinRest/internal/database"
+	"SkinRest/internal/render"
+	"SkinRest/internal/texture"
+	"SkinRest/pkg/models"
+	"image"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// renders are served with strong ETags, clients revalidate with If-None-Match
+const renderCacheControl string = "private, no-cache"
+
+// GetSkinAvatar godoc
+// @Summary Render a skin's face
+// @Description Renders the 8x8 face of a skin with the hat overlay, scaled with nearest-neighbour sampling
+// @Tags skins
+// @Produce png
+// @Param id path int true "Skin ID"
+// @Param size query int false "Avatar size in pixels (8-512, default 64)"
+// @Success 200 {file} binary "PNG avatar"
+// @Success 304 "Not modified, the If-None-Match ETag is current"
+// @Failure 400 {object} gin.H {"error": "Error message"}
+// @Failure 404 {object} gin.H {"error": "Skin not found"}
+// @Failure 502 {object} gin.H {"error": "Could not download the skin texture from its source"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /skins/{id}/avatar [get]
+func GetSkinAvatar(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get skin id from path
+	id, err := idParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get requested size
+	size := render.DefaultAvatarSize
+	if sizeParam := c.Query("size"); sizeParam != "" {
+		size, err = strconv.Atoi(sizeParam)
+		if err != nil || size < render.MinAvatarSize || size > render.MaxAvatarSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrInvalidAvatarSize.Error()})
+			return
+		}
+	}
+
+	serveRender(c, appctx, userdata, id, "avatar", size, func(_ *models.SkinData, img *image.NRGBA) image.Image {
+		return render.Avatar(img, size)
+	})
+}
+
+// GetSkinRender godoc
+// @Summary Render a skin's full body
+// @Description Renders head, body, arms and legs with the overlay layer, from the front, the back or isometrically.
+// @Description Arm width follows the skin type (Classic or Slim).
+// @Tags skins
+// @Produce png
+// @Param id path int true "Skin ID"
+// @Param view query string false "front, back or iso (default front)"
+// @Param scale query int false "Output pixels per skin pixel (1-32, default 8)"
+// @Success 200 {file} binary "PNG render"
+// @Success 304 "Not modified, the If-None-Match ETag is current"
+// @Failure 400 {object} gin.H {"error": "Error message"}
+// @Failure 404 {object} gin.H {"error": "Skin not found"}
+// @Failure 502 {object} gin.H {"error": "Could not download the skin texture from its source"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /skins/{id}/render [get]
+func GetSkinRender(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get skin id from path
+	id, err := idParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get requested view and scale
+	view := c.DefaultQuery("view", render.ViewFront)
+	if !render.ValidView(view) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrInvalidRenderView.Error()})
+		return
+	}
+
+	scale := render.DefaultBodyScale
+	if scaleParam := c.Query("scale"); scaleParam != "" {
+		scale, err = strconv.Atoi(scaleParam)
+		if err != nil || scale < render.MinBodyScale || scale > render.MaxBodyScale {
+			c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrInvalidRenderScale.Error()})
+			return
+		}
+	}
+
+	serveRender(c, appctx, userdata, id, "body-"+view, scale, func(skinData *models.SkinData, img *image.NRGBA) image.Image {
+		// Textures from sources are not converted on the way in
+		return render.Body(texture.ConvertLegacy(img), skinData.Type, view, scale)
+	})
+}
+
+// serveRender answers with a render of one of the user's skins, taken from the
+// render cache when possible. Renders are keyed by texture hash, so a skin
+// whose texture changes gets a new ETag and a fresh render.
+func serveRender(c *gin.Context, appctx *database.AppContext, userdata *models.UserData, id int, kind string, size int, draw func(*models.SkinData, *image.NRGBA) image.Image) {
+	skinData, err := appctx.GetUserSkin(userdata, id)
+	if err != nil {
+		if err == models.ErrSkinNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	// Model type changes the body render without changing the texture
+	if strings.HasPrefix(kind, "body-") {
+		kind += "-" + strings.ToLower(skinData.Type)
+	}
+
+	var img *image.NRGBA
+	hash := skinData.Hash
+	if hash == "" { // texture is not stored, its hash is known once it was downloaded
+		hash, _ = appctx.Sources.Get(skinData.Src)
+	}
+	if hash == "" {
+		var ok bool
+		if img, ok = loadSkinImage(c, appctx, userdata, skinData); !ok {
+			return
+		}
+		hash = texture.Hash(img)
+		appctx.Sources.Put(skinData.Src, hash)
+	}
+
+	key := render.Key{Hash: hash, Kind: kind, Size: size}
+
+	if etagMatches(c.GetHeader("If-None-Match"), key.ETag()) {
+		c.Header("ETag", key.ETag())
+		c.Header("Cache-Control", renderCacheControl)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	data, found := appctx.Renders.Get(key)
+	if !found {
+		if img == nil {
+			var ok bool
+			if img, ok = loadSkinImage(c, appctx, userdata, skinData); !ok {
+				return
+			}
+
+			// a remembered source hash may be stale, render under the current one
+			if skinData.Hash == "" {
+				key.Hash = texture.Hash(img)
+				appctx.Sources.Put(skinData.Src, key.Hash)
+			}
+		}
+
+		data, err = texture.Encode(draw(skinData, img))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			appctx.Logger.Error(err.Error())
+			return
+		}
+
+		appctx.Renders.Put(key, data)
+	}
+
+	c.Header("ETag", key.ETag())
+	c.Header("Cache-Control", renderCacheControl)
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// etagMatches implements the If-None-Match comparison for a strong ETag
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSkinImage returns the texture of a skin, from storage or from its source.
+// On failure it writes the error response and returns false.
+func loadSkinImage(c *gin.Context, appctx *database.AppContext, userdata *models.UserData, skinData *models.SkinData) (*image.NRGBA, bool) {
+	var data []byte
+	var err error
+	if skinData.Texture != "" {
+		data, err = appctx.GetSkinTexture(userdata, skinData.Id)
+	} else {
+		data, err = fetchSkinSource(appctx, skinData.Src)
+	}
+
+	if err != nil {
+		switch err {
+		case models.ErrSkinNotFound, models.ErrTextureNotFound, models.ErrMojangProfileNotFound, models.ErrMojangSkinNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case models.ErrSkinSourceUnreachable, models.ErrSkinTextureTooLarge:
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			appctx.Logger.Error(err.Error())
+		}
+		return nil, false
+	}
+
+	img, err := texture.DecodeSkin(data)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	return img, true
+}
+
+// fetchSkinSource downloads the texture a skin source refers to, either a URL or a Mojang nickname
+func fetchSkinSource(appctx *database.AppContext, src string) ([]byte, error) {
+	skinURL, err := skinSourceURL(appctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return appctx.Fetcher.Fetch(skinURL)
+}
+
+// skinSourceURL returns the texture URL a skin source refers to, resolving Mojang nicknames
+func skinSourceURL(appctx *database.AppContext, src string) (string, error) {
+	if texture.IsURL(src) {
+		return src, nil
+	}
+
+	skinURL, err := appctx.Mojang.SkinURL(src)
+	if err != nil {
+		if err == models.ErrMojangProfileNotFound || err == models.ErrMojangSkinNotFound {
+			return "", err
+		}
+		return "", models.ErrSkinSourceUnreachable
+	}
+
+	return skinURL, nil
+}
@@ -1,19 +1,78 @@
 package api
 
 import (
+	"SkinRest/config"
 	"SkinRest/internal/database"
 	"SkinRest/internal/middleware"
+	"SkinRest/internal/mojang"
+	"SkinRest/internal/render"
+	"SkinRest/internal/skinsync"
+	"SkinRest/internal/storage"
+	"SkinRest/internal/texture"
+	"SkinRest/internal/yggdrasil"
+	"SkinRest/pkg/models"
+	"context"
 	"database/sql"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
 func NewAppCtx(db *sql.DB, logger *zap.Logger) *database.AppContext {
+	cfg := config.GetConfig()
+
+	store, err := storage.New(cfg) // initialize texture storage
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keyPath := cfg.Yggdrasil.KeyPath
+	if keyPath == "" {
+		if cfg.Server.ApiEnv == "local" { // set local or release path to the signing key
+			keyPath = "keys/yggdrasil.pem"
+		} else {
+			keyPath = "/root/keys/yggdrasil.pem"
+		}
+	}
+
+	signer, err := yggdrasil.LoadOrCreateSigner(keyPath) // initialize textures signing key
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	return &database.AppContext{
-		DB:     db,
-		Logger: logger,
+		DB:      db,
+		Logger:  logger,
+		Storage: store,
+		BaseURL: strings.TrimSuffix(cfg.Server.PublicURL, "/"),
+		Mojang:  mojang.NewClient(cfg.Mojang.APIURL, cfg.Mojang.SessionURL, cfg.Mojang.Timeout),
+		Renders: render.NewCache(cfg.Render.CacheEntries, cfg.Render.CacheDir),
+		Sources: render.NewSourceHashes(cfg.Render.CacheEntries, cfg.Render.SourceHashTTL),
+		Signer:  signer,
+		Fetcher: texture.NewFetcher(cfg.Fetch.Timeout, cfg.Fetch.MaxRedirects, cfg.Fetch.AllowPrivate),
+	}
+}
+
+// revoked access tokens are kept until they expire, then dropped in the background
+const revokedTokensPruneInterval = time.Hour
+
+func pruneRevokedTokens(ctx context.Context, appCtx *database.AppContext, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := appCtx.PruneRevokedTokens(); err != nil {
+				appCtx.Logger.Error("revoked tokens prune: " + err.Error())
+			}
+		}
 	}
 }
 
@@ -25,28 +84,97 @@ func ContextMiddleware(appCtx *database.AppContext) gin.HandlerFunc {
 	}
 }
 
-func NewRouter(logger *zap.Logger, DB *sql.DB) *gin.Engine {
+// NewRouter builds the API. Background work, such as the skin sync, runs
+// until ctx is cancelled.
+func NewRouter(ctx context.Context, logger *zap.Logger, DB *sql.DB) *gin.Engine {
 	appCtx := NewAppCtx(DB, logger) // initialize AppContext
 
+	// a guessable secret would hand out the admin role
+	if token := config.GetConfig().Auth.BootstrapToken; token != "" && len(token) < minBootstrapTokenLength {
+		log.Fatalf("AUTH_BOOTSTRAP_TOKEN must be at least %d characters", minBootstrapTokenLength)
+	}
+
+	// keep nickname-sourced skins in step with Mojang in the background
+	if syncCfg := config.GetConfig().Sync; syncCfg.Interval > 0 {
+		scheduler := &skinsync.Scheduler{
+			Store:    appCtx,
+			Mojang:   appCtx.Mojang,
+			Fetcher:  appCtx.Fetcher,
+			Logger:   logger,
+			Interval: syncCfg.Interval,
+			Delay:    syncCfg.Delay,
+		}
+		go scheduler.Run(ctx)
+	}
+
+	go pruneRevokedTokens(ctx, appCtx, revokedTokensPruneInterval)
+
 	r := gin.New()
 	r.Use(gin.Logger(), gin.Recovery())
 	r.Use(ContextMiddleware(appCtx)) // use AppContext for all handlers
+	r.Use(AuthlibInjectorHeader())
 
 	v1 := r.Group("/api/v1")
 	v1.GET("/", HealthCheck)
+	v1.GET("/textures/:key", GetTexture)
 
 	auth := v1.Group("/user")
 
 	auth.POST("/register", RegisterHandler)
 	auth.POST("/login", LoginHandler)
-	auth.GET("/me", middleware.ApiKeyAuth(), middleware.ValidateAuthToken(), AboutMe)
+	auth.POST("/token/refresh", RefreshToken)
+	auth.GET("/me", middleware.ApiKeyAuth(), middleware.RequireSession(), AboutMe)
+	auth.POST("/logout", middleware.ApiKeyAuth(), middleware.RequireSession(), Logout)
+	auth.POST("/logout-all", middleware.ApiKeyAuth(), middleware.RequireSession(), LogoutAll)
+	auth.GET("/sessions", middleware.ApiKeyAuth(), middleware.RequireSession(), ListSessions)
+	auth.DELETE("/sessions/:id", middleware.ApiKeyAuth(), middleware.RequireSession(), RevokeSession)
+	auth.POST("/api-keys", middleware.ApiKeyAuth(), middleware.RequireSession(), CreateApiKey)
+	auth.GET("/api-keys", middleware.ApiKeyAuth(), middleware.RequireSession(), GetApiKeys)
+	auth.DELETE("/api-keys/:id", middleware.ApiKeyAuth(), middleware.RequireSession(), DeleteApiKey)
+	auth.PUT("/me/login", middleware.ApiKeyAuth(), middleware.RequireSession(), ChangeLogin)
+	auth.PUT("/me/active-skin", middleware.ApiKeyAuth(), middleware.RequireSession(), SetActiveSkin)
+	auth.DELETE("/me/active-skin", middleware.ApiKeyAuth(), middleware.RequireSession(), ClearActiveSkin)
+	auth.PUT("/me/active-cape", middleware.ApiKeyAuth(), middleware.RequireSession(), SetActiveCape)
+	auth.DELETE("/me/active-cape", middleware.ApiKeyAuth(), middleware.RequireSession(), ClearActiveCape)
+
+	v1.GET("/users/:login/skin", GetUserActiveSkin)
+
+	v1.POST("/admin/bootstrap", middleware.ApiKeyAuth(), middleware.RequireSession(), BootstrapAdmin)
 
-	skins := v1.Group("/skins", middleware.ApiKeyAuth())
+	admin := v1.Group("/admin", middleware.ApiKeyAuth(), middleware.RequireSession(), middleware.RequireRole(models.RoleAdmin))
+
+	admin.PUT("/users/:login/role", SetUserRole)
+
+	skins := v1.Group("/skins", middleware.ApiKeyAuth(), middleware.RequireScope(models.ScopeSkinsRead, models.ScopeSkinsWrite))
 
 	skins.POST("/add", AddNewSkin)
 	skins.GET("/", GetSkinsCollection)
 	skins.GET("/:id", GetSkin)
+	skins.PUT("/:id", ReplaceSkin)
+	skins.PATCH("/:id", PatchSkin)
 	skins.DELETE("/:id", DeleteSkin)
+	skins.POST("/:id/convert", ConvertSkin)
+	skins.GET("/:id/avatar", GetSkinAvatar)
+	skins.GET("/:id/render", GetSkinRender)
+	skins.GET("/:id/history", GetSkinHistory)
+	skins.GET("/:id/versions", GetSkinVersions)
+	skins.GET("/:id/versions/:v", GetSkinVersion)
+	skins.POST("/:id/versions/:v/restore", RestoreSkinVersion)
+
+	capes := v1.Group("/capes", middleware.ApiKeyAuth(), middleware.RequireScope(models.ScopeCapesRead, models.ScopeCapesWrite))
+
+	capes.POST("/add", AddNewCape)
+	capes.GET("/", GetCapesCollection)
+	capes.GET("/:id", GetCape)
+	capes.DELETE("/:id", DeleteCape)
+
+	// authlib-injector compatible Yggdrasil API for game clients and servers
+	ygg := r.Group(yggdrasilPath)
+
+	ygg.GET("/", YggdrasilMetadata)
+	ygg.GET("/sessionserver/session/minecraft/profile/:uuid", YggdrasilProfile)
+	ygg.POST("/api/profiles/minecraft", YggdrasilProfilesByNames)
+	ygg.GET("/api/users/profiles/minecraft/:name", YggdrasilProfileByName)
 
 	r.SetTrustedProxies(nil)
 
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"SkinRest/internal/database"
+	"SkinRest/pkg/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxUserAgentLength int = 512
+
+// ListSessions godoc
+// @Summary List the user's sessions
+// @Description Lists the devices the user is logged in on, most recently used first. The session of the request is marked Current.
+// @Tags user
+// @Produce json
+// @Success 200 {array} models.Session
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /user/sessions [get]
+func ListSessions(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	sessions, err := appctx.GetUserSessions(userdata, c.GetInt("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession godoc
+// @Summary Log a device out
+// @Description Ends one of the user's sessions: its refresh token stops working at once, and so do its access tokens.
+// @Tags user
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} gin.H {"status": "Success"}
+// @Failure 400 {object} gin.H {"error": "Error message"}
+// @Failure 404 {object} gin.H {"error": "This session does not exist or has ended"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /user/sessions/{id} [delete]
+func RevokeSession(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get claims of the access token the request was made with
+	claims, exists := c.MustGet("tokenClaims").(*database.AccessClaims)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get session id from path
+	id, err := idParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := appctx.RevokeUserSession(userdata, id, claims); err != nil {
+		if err == models.ErrSessionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Success"})
+}
+
+// sessionClient describes the device behind a request
+func sessionClient(c *gin.Context, device string) *models.SessionClient {
+	userAgent := []rune(strings.ToValidUTF8(c.Request.UserAgent(), ""))
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	return &models.SessionClient{
+		Device:    device,
+		UserAgent: string(userAgent),
+		IP:        c.ClientIP(),
+	}
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Ends the session of the access token: the token is revoked and the session's refresh token stops working.
+// @Tags user
+// @Produce json
+// @Success 200 {object} gin.H {"status": "Success"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /user/logout [post]
+func Logout(c *gin.Context) {
+	endSessions(c, (*database.AppContext).EndSession)
+}
+
+// LogoutAll godoc
+// @Summary Log out everywhere
+// @Description Ends every session of the user, on all devices. All their access and refresh tokens stop working.
+// @Tags user
+// @Produce json
+// @Success 200 {object} gin.H {"status": "Success"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /user/logout-all [post]
+func LogoutAll(c *gin.Context) {
+	endSessions(c, (*database.AppContext).EndAllSessions)
+}
+
+func endSessions(c *gin.Context, end func(*database.AppContext, *models.UserData, *database.AccessClaims) error) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get claims of the access token the request was made with
+	claims, exists := c.MustGet("tokenClaims").(*database.AccessClaims)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if err := end(appctx, userdata, claims); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Success"})
+}
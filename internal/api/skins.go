@@ -2,32 +2,43 @@ package api
 
 import (
 	"SkinRest/internal/database"
+	"SkinRest/internal/mojang"
+	"SkinRest/internal/texture"
 	"SkinRest/pkg/models"
 	"fmt"
+	"io"
 
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 const (
 	maxSkinNameLength   int = 30
 	maxSkinSourceLength int = 255
+
+	skinFileField string = "skinfile" // multipart field carrying the PNG texture
 )
 
 // @BasePath /api/v1
 
 // AddNewSkin godoc
 // @Summary Add a new skin
-// @Description Adds a new skin for the authenticated user, returning the created skin data
+// @Description Adds a new skin for the authenticated user, returning the created skin data.
+// @Description The skin may be sent as JSON, or as multipart/form-data with a PNG texture in the "skinfile" field.
 // @Tags skins
 // @Accept json
+// @Accept mpfd
 // @Produce json
 // @Param skin body models.Skin true "Skin object"
-// @Success 201 {object} models.Skin "Created skin data"
+// @Param skinfile formData file false "Skin texture (64x64 or 64x32 RGBA PNG)"
+// @Success 201 {object} models.SkinResult "Created skin data, with warnings if the declared type contradicts the texture"
 // @Failure 400 {object} gin.H {"error": "Missing or invalid fields"}
 // @Failure 404 {object} gin.H {"error": "This user does not exist"}
+// @Failure 502 {object} gin.H {"error": "Could not download the skin texture from its source"}
 // @Failure 500 {object} gin.H {"error": "Internal server error"}
 // @Router /skins/add [post]
 func AddNewSkin(c *gin.Context) {
@@ -46,52 +57,45 @@ func AddNewSkin(c *gin.Context) {
 		return
 	}
 
-	var skin models.Skin
-
-	// Get JSON Body
-	if err := c.ShouldBindJSON(&skin); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid fields: " + err.Error()})
+	// Get JSON body or form fields with the uploaded texture
+	skin, textureData, ok := bindSkin(c)
+	if !ok {
 		return
 	}
 
-	// Validation "skin type" field
-	if skin.Type != "Classic" && skin.Type != "Slim" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrInvalidSkinType.Error()})
-		return
-	}
-
-	if len(skin.Name) > maxSkinNameLength {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("skin name must not exceed %d characters", maxSkinNameLength),
-		})
-		return
-	}
-
-	if len(skin.Src) > maxSkinSourceLength {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("skin source must not exceed %d characters", maxSkinSourceLength),
-		})
+	// Validate the skin, download its source and detect its type
+	skinTexture, warnings, ok := prepareSkin(c, appctx, skin, textureData, true)
+	if !ok {
 		return
 	}
 
 	// Save skin to database
-	skinData, err := appctx.AddNewSkin(userdata, &skin)
+	skinData, err := appctx.AddNewSkin(userdata, skin, skinTexture)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		appctx.Logger.Error(err.Error())
 		return
 	}
 
-	c.JSON(http.StatusCreated, skinData)
+	c.Header("ETag", skinETag(skinData.Version))
+	c.JSON(http.StatusCreated, models.SkinResult{SkinData: *skinData, Warnings: warnings})
 }
 
 // GetSkinsCollection godoc
 // @Summary Retrieve user's skin collection
-// @Description Gets the collection of skins for the authenticated user
+// @Description Gets one page of the collection of skins for the authenticated user, optionally filtered by type and name.
+// @Description Pass the returned next_cursor as cursor to get the following page, with the same filters, sort and order.
 // @Tags skins
 // @Accept json
 // @Produce json
-// @Success 200 {array} models.Skin "List of user's skins"
+// @Param limit query int false "Skins per page (1-100, default 20)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param type query string false "Classic or Slim"
+// @Param name query string false "Case-insensitive substring of the skin name"
+// @Param sort query string false "created or name (default created)"
+// @Param order query string false "asc or desc (default asc)"
+// @Success 200 {object} models.SkinPage "Page of user's skins"
+// @Failure 400 {object} gin.H {"error": "Error message"}
 // @Failure 404 {object} gin.H {"error": "This user does not exist"}
 // @Failure 500 {object} gin.H {"error": "Internal server error"}
 // @Router /skins [get]
@@ -111,15 +115,26 @@ func GetSkinsCollection(c *gin.Context) {
 		return
 	}
 
-	// Get user skins collection from database
-	skins, err := appctx.GetUserSkins(userdata)
+	// Get pagination, filter and sort parameters
+	var query models.SkinQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	// Get a page of user skins collection from database
+	page, err := appctx.GetUserSkinsPage(userdata, &query)
 	if err != nil {
+		if err == models.ErrInvalidCursor {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		appctx.Logger.Error(err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, skins)
+	c.JSON(http.StatusOK, page)
 }
 
 // GetSkin godoc
@@ -178,9 +193,203 @@ func GetSkin(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", skinETag(skinData.Version))
 	c.JSON(http.StatusOK, skinData)
 }
 
+// ReplaceSkin godoc
+// @Summary Replace a skin
+// @Description Replaces the name, type and source of one of the user's skins, keeping its ID. The body is validated like a new skin.
+// @Description The texture is downloaded again only when the source changes or a new "skinfile" is uploaded, otherwise the stored texture is kept.
+// @Description Send the skin's ETag in If-Match to make sure it was not modified since it was read.
+// @Tags skins
+// @Accept json
+// @Accept mpfd
+// @Produce json
+// @Param id path int true "Skin ID"
+// @Param If-Match header string false "ETag of the skin as it was read"
+// @Param skin body models.Skin true "Skin object"
+// @Param skinfile formData file false "Skin texture (64x64 or 64x32 RGBA PNG)"
+// @Success 200 {object} models.SkinResult "Updated skin data, with warnings if the declared type contradicts the texture"
+// @Failure 400 {object} gin.H {"error": "Missing or invalid fields"}
+// @Failure 404 {object} gin.H {"error": "Skin not found"}
+// @Failure 412 {object} gin.H {"error": "The skin was modified since it was read"}
+// @Failure 502 {object} gin.H {"error": "Could not download the skin texture from its source"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /skins/{id} [put]
+func ReplaceSkin(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get skin id from path
+	id, err := idParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get JSON body or form fields with the uploaded texture
+	skin, textureData, ok := bindSkin(c)
+	if !ok {
+		return
+	}
+
+	updateSkin(c, appctx, userdata, id, func(*models.SkinData) *models.Skin { return skin }, textureData)
+}
+
+// PatchSkin godoc
+// @Summary Update a skin
+// @Description Updates some of the fields of one of the user's skins, keeping its ID. Fields left out of the body are unchanged.
+// @Description The texture is downloaded again only when the source changes. An empty "skintype" is detected again from the new texture.
+// @Description Send the skin's ETag in If-Match to make sure it was not modified since it was read.
+// @Tags skins
+// @Accept json
+// @Produce json
+// @Param id path int true "Skin ID"
+// @Param If-Match header string false "ETag of the skin as it was read"
+// @Param skin body models.SkinPatch true "Fields to change"
+// @Success 200 {object} models.SkinResult "Updated skin data, with warnings if the declared type contradicts the texture"
+// @Failure 400 {object} gin.H {"error": "Missing or invalid fields"}
+// @Failure 404 {object} gin.H {"error": "Skin not found"}
+// @Failure 412 {object} gin.H {"error": "The skin was modified since it was read"}
+// @Failure 502 {object} gin.H {"error": "Could not download the skin texture from its source"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /skins/{id} [patch]
+func PatchSkin(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get skin id from path
+	id, err := idParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get JSON Body
+	var patch models.SkinPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid fields: " + err.Error()})
+		return
+	}
+
+	if patch.Name != nil && *patch.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrEmptySkinName.Error()})
+		return
+	}
+
+	// Merge the patch onto the skin as it is stored
+	updateSkin(c, appctx, userdata, id, func(current *models.SkinData) *models.Skin {
+		skin := &models.Skin{Name: current.Name, Type: current.Type, Src: current.Src}
+		if patch.Name != nil {
+			skin.Name = *patch.Name
+		}
+		if patch.Type != nil {
+			skin.Type = *patch.Type
+		}
+		if patch.Src != nil {
+			skin.Src = *patch.Src
+		}
+		return skin
+	}, nil)
+}
+
+// updateSkin applies the skin built by merge from the stored one, checking
+// If-Match against the stored version first. The texture is downloaded again
+// only when the source changes and no file was uploaded.
+func updateSkin(c *gin.Context, appctx *database.AppContext, userdata *models.UserData, id int, merge func(*models.SkinData) *models.Skin, textureData []byte) {
+	current, err := appctx.GetUserSkin(userdata, id)
+	if err != nil {
+		if err == models.ErrSkinNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" && !ifMatchMatches(ifMatch, skinETag(current.Version)) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": models.ErrSkinVersionConflict.Error()})
+		return
+	}
+
+	skin := merge(current)
+
+	// Clearing the source of a skin without a stored texture leaves it with nothing
+	fetch := textureData == nil && skin.Src != current.Src && (skin.Src != "" || current.Hash == "")
+
+	// The stored texture is kept, and with it its type unless one is given
+	if textureData == nil && !fetch && skin.Type == "" {
+		skin.Type = current.Type
+	}
+
+	// Validate the skin, download its new source and detect its type
+	skinTexture, warnings, ok := prepareSkin(c, appctx, skin, textureData, fetch)
+	if !ok {
+		return
+	}
+
+	// Save skin to database, unless it changed since it was read
+	skinData, err := appctx.UpdateUserSkin(userdata, id, skin, skinTexture, current.Version)
+	if err != nil {
+		switch err {
+		case models.ErrSkinNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case models.ErrSkinVersionConflict:
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			appctx.Logger.Error(err.Error())
+		}
+		return
+	}
+
+	c.Header("ETag", skinETag(skinData.Version))
+	c.JSON(http.StatusOK, models.SkinResult{SkinData: *skinData, Warnings: warnings})
+}
+
+// skinETag is the entity tag of a skin, which changes with every new version
+func skinETag(version int) string {
+	return fmt.Sprintf(`"v%d"`, version)
+}
+
+// ifMatchMatches implements the If-Match comparison, which is strong: a weak
+// candidate never matches, as it does not promise the same representation
+func ifMatchMatches(ifMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
 // DeleteSkin godoc
 // @Summary Delete a specific skin by ID
 // @Description Deletes the specified skin for the authenticated user using the skin ID
@@ -237,3 +446,359 @@ func DeleteSkin(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"status": "Success"})
 }
+
+// ConvertSkin godoc
+// @Summary Convert a legacy skin to the 64x64 layout
+// @Description Converts the stored 64x32 texture of a skin to the 1.8+ 64x64 layout, mirroring the right arm and leg into the left-limb slots.
+// @Description The converted texture becomes canonical, the previous one stays available as OriginalTexture.
+// @Tags skins
+// @Produce json
+// @Param id path int true "Skin ID"
+// @Success 200 {object} models.SkinData "Converted skin"
+// @Failure 400 {object} gin.H {"error": "Error message"}
+// @Failure 404 {object} gin.H {"error": "Skin not found"}
+// @Failure 409 {object} gin.H {"error": "Skin texture is already in the 64x64 format"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /skins/{id}/convert [post]
+func ConvertSkin(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get skin id from path
+	id, err := idParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get stored texture
+	data, err := appctx.GetSkinTexture(userdata, id)
+	if err != nil {
+		if err == models.ErrSkinNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err == models.ErrTextureNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrSkinHasNoTexture.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	img, err := texture.DecodeSkin(data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	if !texture.IsLegacy(img) {
+		c.JSON(http.StatusConflict, gin.H{"error": models.ErrSkinNotLegacy.Error()})
+		return
+	}
+
+	skinTexture, err := texture.PrepareSkin(img, data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	// Save converted texture
+	skinData, err := appctx.SetSkinTexture(userdata, id, skinTexture)
+	if err != nil {
+		if err == models.ErrSkinNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, skinData)
+}
+
+// GetTexture godoc
+// @Summary Download a skin texture
+// @Description Returns the stored PNG texture by its key. Texture keys never change, so the response is cacheable forever.
+// @Tags skins
+// @Produce png
+// @Param key path string true "Texture key"
+// @Success 200 {file} binary "PNG texture"
+// @Failure 404 {object} gin.H {"error": "This texture does not exist"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /textures/{key} [get]
+func GetTexture(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get texture from storage
+	data, err := appctx.GetTexture(c.Param("key"))
+	if err != nil {
+		if err == models.ErrTextureNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// bindSkin reads a skin sent as JSON, or as multipart/form-data with an
+// optional texture file. On failure it writes the error response and returns false.
+func bindSkin(c *gin.Context) (*models.Skin, []byte, bool) {
+	var skin models.Skin
+	var textureData []byte
+
+	if c.ContentType() == binding.MIMEMultipartPOSTForm {
+		// Get form fields
+		if err := c.ShouldBindWith(&skin, binding.FormMultipart); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid fields: " + err.Error()})
+			return nil, nil, false
+		}
+
+		// Get uploaded texture
+		data, err := readTextureFile(c, skinFileField)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, nil, false
+		}
+		textureData = data
+	} else {
+		// Get JSON Body
+		if err := c.ShouldBindJSON(&skin); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid fields: " + err.Error()})
+			return nil, nil, false
+		}
+	}
+
+	return &skin, textureData, true
+}
+
+// sourceErrorStatus is the status of a failed skin source download: the
+// upstream failing is a bad gateway, anything else is wrong with the source
+func sourceErrorStatus(err error) int {
+	if err == models.ErrSkinSourceUnreachable {
+		return http.StatusBadGateway
+	}
+	return http.StatusBadRequest
+}
+
+// prepareSkin validates a skin and its uploaded texture the way every skin
+// is validated before it is saved. Without an upload the texture of the
+// source is downloaded if fetch is set, otherwise the stored texture is kept
+// and nil is returned for it. An empty type is detected from the new texture.
+// On failure it writes the error response and returns false.
+func prepareSkin(c *gin.Context, appctx *database.AppContext, skin *models.Skin, textureData []byte, fetch bool) (*models.SkinTexture, []string, bool) {
+	// Validation "skin type" field, it may be left out and detected from the texture
+	if skin.Type != "" && skin.Type != models.SkinTypeClassic && skin.Type != models.SkinTypeSlim {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrInvalidSkinType.Error()})
+		return nil, nil, false
+	}
+
+	if len(skin.Name) > maxSkinNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("skin name must not exceed %d characters", maxSkinNameLength),
+		})
+		return nil, nil, false
+	}
+
+	if len(skin.Src) > maxSkinSourceLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("skin source must not exceed %d characters", maxSkinSourceLength),
+		})
+		return nil, nil, false
+	}
+
+	if fetch && skin.Src == "" && textureData == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrSkinSourceMissing.Error()})
+		return nil, nil, false
+	}
+
+	if skin.Src != "" && !texture.IsURL(skin.Src) && !mojang.ValidName(skin.Src) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrInvalidSkinSource.Error()})
+		return nil, nil, false
+	}
+
+	// Model type Mojang reports for a nickname source, and where the texture was downloaded from
+	var sourceModel, sourceURL string
+
+	// Download the texture of the source when no file was uploaded
+	if fetch && textureData == nil {
+		skinURL := skin.Src
+		if !texture.IsURL(skin.Src) {
+			mojangSkin, err := appctx.Mojang.Skin(skin.Src)
+			if err != nil {
+				c.JSON(sourceErrorStatus(err), gin.H{"error": err.Error()})
+				return nil, nil, false
+			}
+			skinURL, sourceModel = mojangSkin.URL, mojangSkin.Model
+		}
+
+		data, err := appctx.Fetcher.Fetch(skinURL)
+		if err != nil {
+			c.JSON(sourceErrorStatus(err), gin.H{"error": err.Error()})
+			return nil, nil, false
+		}
+		textureData, sourceURL = data, skinURL
+	}
+
+	var warnings []string
+	var skinTexture *models.SkinTexture
+
+	// Validation skin texture and detection of its model
+	if textureData != nil {
+		img, err := texture.DecodeSkin(textureData)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, nil, false
+		}
+
+		// Legacy 64x32 skins are stored converted, keeping the upload as the original
+		skinTexture, err = texture.PrepareSkin(img, textureData)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			appctx.Logger.Error(err.Error())
+			return nil, nil, false
+		}
+		skinTexture.SourceURL = sourceURL
+
+		detected := sourceModel
+		if detected == "" {
+			detected = texture.DetectModel(img)
+		}
+
+		if skin.Type == "" {
+			skin.Type = detected
+		} else if skin.Type != detected && sourceModel != "" {
+			warnings = append(warnings, fmt.Sprintf("skin type %s does not match the Mojang profile, which wears a %s skin", skin.Type, detected))
+		} else if skin.Type != detected {
+			warnings = append(warnings, fmt.Sprintf("skin type %s does not match the texture, which looks %s", skin.Type, detected))
+		}
+	}
+
+	if skin.Type == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrSkinTypeUndetected.Error()})
+		return nil, nil, false
+	}
+
+	return skinTexture, warnings, true
+}
+
+// readTextureFile returns the contents of the texture uploaded in field, or nil if no file was sent
+func readTextureFile(c *gin.Context, field string) ([]byte, error) {
+	header, err := c.FormFile(field)
+	if err != nil {
+		if err == http.ErrMissingFile {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if header.Size > texture.MaxTextureSize {
+		return nil, models.ErrSkinTextureTooLarge
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, texture.MaxTextureSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > texture.MaxTextureSize {
+		return nil, models.ErrSkinTextureTooLarge
+	}
+
+	return data, nil
+}
+
+// idParam reads and validates the ":id" path parameter
+func idParam(c *gin.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return 0, models.ErrInvalidIdFormat
+	}
+
+	if id < 1 {
+		return 0, models.ErrInvalidIdValue
+	}
+
+	return id, nil
+}
+
+// GetSkinHistory godoc
+// @Summary Get a skin's texture history
+// @Description Lists the texture changes picked up by the periodic re-sync of a nickname-sourced skin, newest first
+// @Tags skins
+// @Produce json
+// @Param id path int true "Skin ID"
+// @Success 200 {array} models.SkinHistoryEntry
+// @Failure 400 {object} gin.H {"error": "Error message"}
+// @Failure 404 {object} gin.H {"error": "Skin not found"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /skins/{id}/history [get]
+func GetSkinHistory(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get skin id from path
+	id, err := idParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	history, err := appctx.GetSkinHistory(userdata, id)
+	if err != nil {
+		if err == models.ErrSkinNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
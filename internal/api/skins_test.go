@@ -0,0 +1,19 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatchMatches(t *testing.T) {
+	etag := skinETag(3)
+
+	assert.True(t, ifMatchMatches(`"v3"`, etag))
+	assert.True(t, ifMatchMatches(`"v1", "v3"`, etag))
+	assert.True(t, ifMatchMatches("*", etag))
+
+	// If-Match uses the strong comparison, a weak tag never matches
+	assert.False(t, ifMatchMatches(`W/"v3"`, etag))
+	assert.False(t, ifMatchMatches(`"v2"`, etag))
+}
@@ -2,8 +2,10 @@ package api
 
 import (
 	"SkinRest/internal/database"
+	"SkinRest/internal/texture"
 	"SkinRest/pkg/models"
 	"fmt"
+	"net/url"
 
 	"net/http"
 
@@ -80,7 +82,7 @@ func RegisterHandler(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param user body models.User true "User login object"
-// @Success 200 {object} gin.H {"token": "JWT Token"}
+// @Success 200 {object} models.TokenPair "Access and refresh tokens"
 // @Failure 400 {object} gin.H {"error": "Missing or invalid fields"}
 // @Failure 404 {object} gin.H {"error": "This user does not exist"}
 // @Failure 500 {object} gin.H {"error": "Internal server error"}
@@ -130,26 +132,65 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 
-	// validate token
-	if err := database.CheckToken(userData.Token); err != nil {
-		if err.Error() == models.ErrTokenExpired.Error() {
+	// Open a session with a fresh pair of tokens
+	tokens, err := appctx.CreateSession(userData, sessionClient(c, user.Device))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
 
-			newToken, err := appctx.UpdateUserToken(&user)
-			if err != nil {
-				if err.Error() == models.ErrUserNotFound.Error() {
-					c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-					return
-				}
-				appctx.Logger.Error(err.Error())
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-				return
-			}
-			c.JSON(http.StatusOK, gin.H{"token": newToken})
-		}
+	c.JSON(http.StatusOK, tokens)
+
+}
+
+// RefreshToken godoc
+// @Summary Refresh the access token
+// @Description Exchanges a refresh token for a new access token and a new refresh token. Each refresh token works once:
+// @Description presenting one that was already used revokes its whole session.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param token body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.TokenPair
+// @Failure 400 {object} gin.H {"error": "Missing or invalid fields"}
+// @Failure 401 {object} gin.H {"error": "Invalid refresh token"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /user/token/refresh [post]
+func RefreshToken(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": userData.Token})
+	var request models.RefreshRequest
+
+	// Get JSON Body
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid fields: " + err.Error()})
+		return
+	}
 
+	// Rotate the refresh token
+	tokens, err := appctx.RefreshSession(request.RefreshToken, sessionClient(c, ""))
+	if err != nil {
+		switch err {
+		case models.ErrInvalidRefreshToken, models.ErrRefreshTokenExpired, models.ErrUserNotFound:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case models.ErrRefreshTokenReused:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			appctx.Logger.Warn("refresh token reused, session revoked")
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			appctx.Logger.Error(err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
 }
 
 // AboutMe godoc
@@ -186,11 +227,275 @@ func AboutMe(c *gin.Context) {
 		return
 	}
 
+	// Get user capes collection from database
+	capes, err := appctx.GetUserCapes(userdata)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	// Get logins the user has given up
+	previousLogins, err := appctx.GetLoginHistory(userdata)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
 	// Create user information object
 	userInfo := models.UserInfo{
-		Login: userdata.Login,
-		Skins: skins,
+		Login:      userdata.Login,
+		Role:       userdata.Role,
+		ActiveSkin: userdata.ActiveSkin,
+		ActiveCape: userdata.ActiveCape,
+		Skins:      skins,
+		Capes:      capes,
+
+		PreviousLogins: previousLogins,
+
+		CreatedAt: userdata.CreatedAt,
+		UpdatedAt: userdata.UpdatedAt,
 	}
 
 	c.JSON(http.StatusOK, userInfo)
 }
+
+// ChangeLogin godoc
+// @Summary Change the user's login
+// @Description Renames the authenticated user and returns a new access token issued to the new login.
+// @Description The old login is kept in the user's name history: public lookups by it are redirected to the new login until someone else takes it.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param login body models.NewLogin true "New login"
+// @Success 200 {object} gin.H {"login": "New login", "token": "JWT Token"}
+// @Failure 400 {object} gin.H {"error": "Missing or invalid fields"}
+// @Failure 409 {object} gin.H {"error": "This login is already taken"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /user/me/login [put]
+func ChangeLogin(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	var newLogin models.NewLogin
+
+	// Get JSON Body
+	if err := c.ShouldBindJSON(&newLogin); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid fields: " + err.Error()})
+		return
+	}
+
+	if len(newLogin.Login) > maxLoginLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("login must not exceed %d characters", maxLoginLength),
+		})
+		return
+	}
+
+	// Rename user in database
+	if err := appctx.ChangeUserLogin(userdata, newLogin.Login); err != nil {
+		switch err {
+		case models.ErrSameLogin:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case models.ErrLoginTaken:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case models.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			appctx.Logger.Error(err.Error())
+		}
+		return
+	}
+
+	// The access token names the login it was issued to, reissue it for the current session
+	userdata.Login = newLogin.Login
+	token, err := database.GenerateAccessToken(userdata, c.GetInt("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"login": newLogin.Login, "token": token})
+}
+
+// SetActiveSkin godoc
+// @Summary Select the active skin
+// @Description Selects which of the user's skins is worn in game and served to game clients
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param skin body models.ActiveSkin true "Skin to wear"
+// @Success 200 {object} models.SkinData
+// @Failure 400 {object} gin.H {"error": "Missing or invalid fields"}
+// @Failure 404 {object} gin.H {"error": "Skin not found"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /user/me/active-skin [put]
+func SetActiveSkin(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	var activeSkin models.ActiveSkin
+
+	// Get JSON Body
+	if err := c.ShouldBindJSON(&activeSkin); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid fields: " + err.Error()})
+		return
+	}
+
+	skinData, err := appctx.SetActiveSkin(userdata, activeSkin.SkinId)
+	if err != nil {
+		if err == models.ErrSkinNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, skinData)
+}
+
+// ClearActiveSkin godoc
+// @Summary Take the active skin off
+// @Description Leaves the user without a skin in game, clients show their default skin
+// @Tags user
+// @Produce json
+// @Success 200 {object} gin.H {"status": "Success"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /user/me/active-skin [delete]
+func ClearActiveSkin(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if err := appctx.ClearActiveSkin(userdata); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Success"})
+}
+
+// GetUserActiveSkin godoc
+// @Summary Get a user's active skin texture
+// @Description Public endpoint for game clients, redirects to the stored texture of the skin the user wears.
+// @Description A skin without a stored texture is downloaded from its source and served directly, clients are never sent to the source.
+// @Description A login the user has given up redirects to the same endpoint under the user's current login.
+// @Tags user
+// @Produce png
+// @Param login path string true "User login"
+// @Success 200 {file} binary "PNG texture of a skin that is not stored"
+// @Success 302 "Redirect to the skin texture, or to the current login of a renamed user"
+// @Failure 404 {object} gin.H {"error": "Error message"}
+// @Failure 502 {object} gin.H {"error": "Could not download the skin texture from its source"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /users/{login}/skin [get]
+func GetUserActiveSkin(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	login := c.Param("login")
+
+	skinData, err := appctx.GetActiveSkin(login)
+	if err == models.ErrUserNotFound {
+		// The login may have been given up, send the client on to its new owner
+		current, renameErr := appctx.GetRenamedLogin(login)
+		if renameErr == nil {
+			c.Header("Cache-Control", "no-cache")
+			c.Redirect(http.StatusFound, "/api/v1/users/"+url.PathEscape(current)+"/skin")
+			return
+		}
+		if renameErr != models.ErrUserNotFound {
+			err = renameErr
+		}
+	}
+	if err != nil {
+		if err == models.ErrUserNotFound || err == models.ErrActiveSkinNotSet {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	// The active skin can change at any time, the texture it points to cannot
+	c.Header("Cache-Control", "no-cache")
+
+	if skinData.Texture != "" {
+		c.Redirect(http.StatusFound, skinData.Texture)
+		return
+	}
+
+	serveSkinSource(c, appctx, skinData.Src)
+}
+
+// serveSkinSource downloads the texture of a skin source and serves it.
+// Sources are user supplied, they are proxied instead of redirecting clients there.
+func serveSkinSource(c *gin.Context, appctx *database.AppContext, src string) {
+	data, err := fetchSkinSource(appctx, src)
+	if err != nil {
+		switch err {
+		case models.ErrMojangProfileNotFound, models.ErrMojangSkinNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case models.ErrSkinSourceUnreachable, models.ErrSkinTextureTooLarge:
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			appctx.Logger.Error(err.Error())
+		}
+		return
+	}
+
+	if _, err := texture.DecodeSkin(data); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, "image/png", data)
+}
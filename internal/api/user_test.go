@@ -0,0 +1,74 @@
+package api
+
+import (
+	"SkinRest/internal/database"
+	"SkinRest/internal/texture"
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// encodePNG is an RGBA PNG of the given size
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	// the corner stays transparent, so the PNG keeps its alpha channel
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 7; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestServeSkinSource(t *testing.T) {
+	skin := encodePNG(t, 64, 64)
+	icon := encodePNG(t, 16, 16)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/skin.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(skin)
+	})
+	mux.HandleFunc("/icon.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(icon)
+	})
+	source := httptest.NewServer(mux)
+	defer source.Close()
+
+	appctx := &database.AppContext{
+		Logger:  zap.NewNop(),
+		Fetcher: texture.NewFetcher(time.Second, 3, true),
+	}
+
+	serve := func(src string) *httptest.ResponseRecorder {
+		gin.SetMode(gin.TestMode)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		serveSkinSource(c, appctx, src)
+		return w
+	}
+
+	// the texture is proxied, the client never learns where the source is
+	w := serve(source.URL + "/skin.png")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.Equal(t, skin, w.Body.Bytes())
+
+	assert.Equal(t, http.StatusBadGateway, serve(source.URL+"/icon.png").Code)
+	assert.Equal(t, http.StatusBadGateway, serve(source.URL+"/missing.png").Code)
+}
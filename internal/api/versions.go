@@ -0,0 +1,177 @@
+package api
+
+import (
+	"SkinRest/internal/database"
+	"SkinRest/pkg/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSkinVersions godoc
+// @Summary List a skin's versions
+// @Description Lists every version of one of the user's skins, newest first. A version is recorded each time the skin's texture, name or type changes.
+// @Tags skins
+// @Produce json
+// @Param id path int true "Skin ID"
+// @Success 200 {array} models.SkinVersion
+// @Failure 400 {object} gin.H {"error": "Error message"}
+// @Failure 404 {object} gin.H {"error": "Skin not found"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /skins/{id}/versions [get]
+func GetSkinVersions(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get skin id from path
+	id, err := idParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	versions, err := appctx.GetSkinVersions(userdata, id)
+	if err != nil {
+		if err == models.ErrSkinNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// GetSkinVersion godoc
+// @Summary Get one version of a skin
+// @Tags skins
+// @Produce json
+// @Param id path int true "Skin ID"
+// @Param v path int true "Version number"
+// @Success 200 {object} models.SkinVersion
+// @Failure 400 {object} gin.H {"error": "Error message"}
+// @Failure 404 {object} gin.H {"error": "Error message"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /skins/{id}/versions/{v} [get]
+func GetSkinVersion(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get skin id and version from path
+	id, err := idParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := versionParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	skinVersion, err := appctx.GetSkinVersion(userdata, id, version)
+	if err != nil {
+		if err == models.ErrSkinNotFound || err == models.ErrSkinVersionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, skinVersion)
+}
+
+// RestoreSkinVersion godoc
+// @Summary Roll a skin back to an earlier version
+// @Description Restores the texture, name, type and source of a version. The restored state is recorded as a new version.
+// @Tags skins
+// @Produce json
+// @Param id path int true "Skin ID"
+// @Param v path int true "Version number"
+// @Success 200 {object} models.SkinData
+// @Failure 400 {object} gin.H {"error": "Error message"}
+// @Failure 404 {object} gin.H {"error": "Error message"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /skins/{id}/versions/{v}/restore [post]
+func RestoreSkinVersion(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get user data from this context
+	userdata, exists := c.MustGet("userData").(*models.UserData)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Get skin id and version from path
+	id, err := idParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := versionParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	skinData, err := appctx.RestoreSkinVersion(userdata, id, version)
+	if err != nil {
+		if err == models.ErrSkinNotFound || err == models.ErrSkinVersionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, skinData)
+}
+
+// versionParam reads and validates the ":v" path parameter
+func versionParam(c *gin.Context) (int, error) {
+	version, err := strconv.Atoi(c.Param("v"))
+	if err != nil || version < 1 {
+		return 0, models.ErrInvalidVersion
+	}
+
+	return version, nil
+}
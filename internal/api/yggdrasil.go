@@ -0,0 +1,239 @@
+package api
+
+import (
+	"SkinRest/config"
+	"SkinRest/internal/database"
+	"SkinRest/internal/yggdrasil"
+	"SkinRest/pkg/models"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	yggdrasilPath string = "/api/yggdrasil"
+
+	// the lookup endpoint of the Mojang API accepts at most 10 names per request
+	maxProfileNames int = 10
+)
+
+// AuthlibInjectorHeader advertises the Yggdrasil API on every response, so
+// launchers can be pointed at the server root instead of the full API path
+func AuthlibInjectorHeader() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("X-Authlib-Injector-API-Location", yggdrasilPath+"/")
+		c.Next()
+	}
+}
+
+// YggdrasilMetadata godoc
+// @Summary Yggdrasil API metadata
+// @Description Describes the server to authlib-injector: its name, the domains textures are served from and the public key textures are signed with
+// @Tags yggdrasil
+// @Produce json
+// @Success 200 {object} yggdrasil.Metadata
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /yggdrasil/ [get]
+func YggdrasilMetadata(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	publicKey, err := appctx.Signer.PublicKeyPEM()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	var skinDomains []string
+	if base, err := url.Parse(appctx.BaseURL); err == nil && base.Hostname() != "" {
+		skinDomains = append(skinDomains, base.Hostname())
+	}
+
+	c.JSON(http.StatusOK, yggdrasil.Metadata{
+		Meta: map[string]any{
+			"serverName":              config.GetConfig().Yggdrasil.ServerName,
+			"implementationName":      "SkinRest",
+			"implementationVersion":   "1.0",
+			"feature.non_email_login": true,
+		},
+		SkinDomains:        skinDomains,
+		SignaturePublickey: publicKey,
+	})
+}
+
+// YggdrasilProfile godoc
+// @Summary Get a player profile with textures
+// @Description Returns the profile with its signed "textures" property, pointing at the player's skin.
+// @Description Answers 204 No Content for unknown profiles, like the Mojang session server.
+// @Tags yggdrasil
+// @Produce json
+// @Param uuid path string true "Profile UUID, with or without dashes"
+// @Param unsigned query bool false "Omit the property signature (default true)"
+// @Success 200 {object} yggdrasil.Profile
+// @Success 204 "Profile not found"
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /yggdrasil/sessionserver/session/minecraft/profile/{uuid} [get]
+func YggdrasilProfile(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	uuid := strings.ToLower(strings.ReplaceAll(c.Param("uuid"), "-", ""))
+
+	profile, err := appctx.GetProfileByUUID(uuid)
+	if err != nil {
+		if err == models.ErrUserNotFound {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	property, err := texturesProperty(profile)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	// Game servers ask for signed textures with unsigned=false
+	if c.Query("unsigned") == "false" {
+		property.Signature, err = appctx.Signer.Sign(property.Value)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			appctx.Logger.Error(err.Error())
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, yggdrasil.Profile{
+		Id:         profile.Id,
+		Name:       profile.Name,
+		Properties: []yggdrasil.Property{property},
+	})
+}
+
+// YggdrasilProfilesByNames godoc
+// @Summary Look up profiles by name
+// @Description Returns the id and name of every known player in the list; unknown names are left out
+// @Tags yggdrasil
+// @Accept json
+// @Produce json
+// @Param names body []string true "Player names (at most 10)"
+// @Success 200 {array} yggdrasil.ProfileRef
+// @Failure 400 {object} gin.H {"error": "Error message"}
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /yggdrasil/api/profiles/minecraft [post]
+func YggdrasilProfilesByNames(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	var names []string
+	if err := c.ShouldBindJSON(&names); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(names) > maxProfileNames {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrTooManyProfileNames.Error()})
+		return
+	}
+
+	profiles, err := appctx.GetProfilesByNames(names)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	refs := make([]yggdrasil.ProfileRef, 0, len(profiles))
+	for _, profile := range profiles {
+		refs = append(refs, yggdrasil.ProfileRef{Id: profile.Id, Name: profile.Name})
+	}
+
+	c.JSON(http.StatusOK, refs)
+}
+
+// YggdrasilProfileByName godoc
+// @Summary Look up a profile by name
+// @Description Returns the id and name of a player, or 204 No Content if there is no such player
+// @Tags yggdrasil
+// @Produce json
+// @Param name path string true "Player name"
+// @Success 200 {object} yggdrasil.ProfileRef
+// @Success 204 "Profile not found"
+// @Failure 500 {object} gin.H {"error": "Internal server error"}
+// @Router /yggdrasil/api/users/profiles/minecraft/{name} [get]
+func YggdrasilProfileByName(c *gin.Context) {
+
+	// Get AppContext from this context
+	appctx, exists := c.MustGet("appCtx").(*database.AppContext)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	profile, err := appctx.GetProfileByName(c.Param("name"))
+	if err != nil {
+		if err == models.ErrUserNotFound {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, yggdrasil.ProfileRef{Id: profile.Id, Name: profile.Name})
+}
+
+// texturesProperty builds the unsigned "textures" property of a profile
+func texturesProperty(profile *models.Profile) (yggdrasil.Property, error) {
+	textures := yggdrasil.Textures{
+		Timestamp:   time.Now().UnixMilli(),
+		ProfileId:   profile.Id,
+		ProfileName: profile.Name,
+		Textures:    map[string]yggdrasil.Texture{},
+	}
+
+	if profile.Skin != nil {
+		skin := yggdrasil.Texture{URL: profile.Skin.Texture}
+		if profile.Skin.Type == models.SkinTypeSlim {
+			skin.Metadata = map[string]string{"model": "slim"}
+		}
+		textures.Textures["SKIN"] = skin
+	}
+
+	if profile.Cape != nil {
+		textures.Textures["CAPE"] = yggdrasil.Texture{URL: profile.Cape.Texture}
+	}
+
+	value, err := json.Marshal(textures)
+	if err != nil {
+		return yggdrasil.Property{}, err
+	}
+
+	return yggdrasil.Property{Name: "textures", Value: base64.StdEncoding.EncodeToString(value)}, nil
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"SkinRest/pkg/models"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Personal API keys let scripts act for a user without logging in. Keys
+// carry a fixed prefix so secret scanners can recognise them, and like
+// refresh tokens only their hash is stored.
+
+const (
+	ApiKeyPrefix string = "skr_"
+
+	maxApiKeys       int = 25
+	apiKeyPrefixSize int = len(ApiKeyPrefix) + 8 // part of the key kept in clear to tell keys apart
+)
+
+// IsApiKey reports whether a credential looks like an API key rather than an access token
+func IsApiKey(credential string) bool {
+	return strings.HasPrefix(credential, ApiKeyPrefix)
+}
+
+func (m *AppContext) CreateApiKey(userData *models.UserData, apiKey *models.NewApiKey) (*models.ApiKeyCreated, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	key := ApiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Serialise key creation per user so the limit holds
+	if _, err := tx.Exec("SELECT 1 FROM userstable WHERE user_id = $1 FOR UPDATE", userData.Id); err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM apikeystable WHERE user_id = $1", userData.Id).Scan(&count); err != nil {
+		return nil, err
+	}
+
+	if count >= maxApiKeys {
+		return nil, models.ErrTooManyApiKeys
+	}
+
+	created := &models.ApiKeyCreated{Key: key}
+	created.Name = apiKey.Name
+	created.Prefix = key[:apiKeyPrefixSize]
+	created.Scopes = apiKey.Scopes
+
+	err = tx.QueryRow(`INSERT INTO apikeystable (user_id, key_name, key_hash, key_prefix, scopes)
+        VALUES ($1, $2, $3, $4, $5) RETURNING key_id, created_at`,
+		userData.Id, apiKey.Name, hashSecret(key), created.Prefix, pq.Array(apiKey.Scopes)).Scan(&created.Id, &created.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (m *AppContext) GetUserApiKeys(userData *models.UserData) ([]models.ApiKey, error) {
+	var apiKeys []models.ApiKey
+
+	rows, err := m.DB.Query("SELECT key_id, key_name, key_prefix, scopes, created_at, last_used_at FROM apikeystable WHERE user_id = $1 ORDER BY key_id", userData.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var apiKey models.ApiKey
+		if err := rows.Scan(&apiKey.Id, &apiKey.Name, &apiKey.Prefix, pq.Array(&apiKey.Scopes), &apiKey.CreatedAt, &apiKey.LastUsedAt); err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+// DeleteUserApiKey revokes one of the user's keys, it stops working at once
+func (m *AppContext) DeleteUserApiKey(userData *models.UserData, id int) error {
+	res, err := m.DB.Exec("DELETE FROM apikeystable WHERE key_id = $1 AND user_id = $2", id, userData.Id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrApiKeyNotFound
+	}
+
+	return nil
+}
+
+// GetApiKeyUser returns the user an API key acts for and the scopes it grants
+func (m *AppContext) GetApiKeyUser(key string) (*models.UserData, []string, error) {
+	var keyId int
+	var scopes []string
+
+	err := m.DB.QueryRow("SELECT key_id, scopes FROM apikeystable WHERE key_hash = $1", hashSecret(key)).Scan(&keyId, pq.Array(&scopes))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, models.ErrInvalidApiKey
+		}
+		return nil, nil, err
+	}
+
+	userData, err := scanUser(m.DB.QueryRow("SELECT "+userColumns+" FROM userstable WHERE user_id = (SELECT user_id FROM apikeystable WHERE key_id = $1)", keyId))
+	if err != nil {
+		if err == models.ErrUserNotFound { // key deleted in the meantime
+			return nil, nil, models.ErrInvalidApiKey
+		}
+		return nil, nil, err
+	}
+
+	// last use is kept to the minute, not every request needs a write
+	_, err = m.DB.Exec("UPDATE apikeystable SET last_used_at = now() WHERE key_id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')", keyId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return userData, scopes, nil
+}
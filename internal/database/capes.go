@@ -0,0 +1,163 @@
+package database
+
+import (
+	"SkinRest/pkg/models"
+	"database/sql"
+)
+
+// Capes live beside skins in capestable. Unlike skins they always have a
+// stored texture, which shares the reference-counted blobs of texturestable.
+
+func (m *AppContext) AddNewCape(userData *models.UserData, cape *models.Cape, data []byte) (*models.CapeData, error) {
+	var capeId int
+
+	tx, err := m.beginTextureTx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blobKey, err := m.acquireTexture(tx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow("INSERT INTO capestable (owner_id, cape_name, blob_key) VALUES ($1, $2, $3) RETURNING cape_id", userData.Id, cape.Name, blobKey).Scan(&capeId)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first cape a user adds is worn right away
+	_, err = tx.Exec("UPDATE userstable SET active_cape_id = $1, updated_at = now() WHERE user_id = $2 AND active_cape_id IS NULL", capeId, userData.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &models.CapeData{
+		Id:      capeId,
+		Name:    cape.Name,
+		Texture: m.textureURL(blobKey),
+		Hash:    blobKey,
+	}, nil
+}
+
+func (m *AppContext) GetUserCapes(userData *models.UserData) ([]models.CapeData, error) {
+	var capes []models.CapeData
+
+	rows, err := m.DB.Query("SELECT "+capeColumns+" FROM capestable WHERE owner_id = $1", userData.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		cape, err := m.scanCape(rows)
+		if err != nil {
+			return nil, err
+		}
+		capes = append(capes, *cape)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return capes, nil
+}
+
+func (m *AppContext) GetUserCape(userData *models.UserData, id int) (*models.CapeData, error) {
+	capeData, err := m.scanCape(m.DB.QueryRow("SELECT "+capeColumns+" FROM capestable WHERE cape_id = $1 AND owner_id = $2", id, userData.Id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrCapeNotFound
+		}
+		return nil, err
+	}
+
+	return capeData, nil
+}
+
+func (m *AppContext) DeleteUserCape(userData *models.UserData, id int) error {
+	var blobKey string
+
+	tx, err := m.beginTextureTx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("DELETE FROM capestable WHERE cape_id = $1 AND owner_id = $2 RETURNING blob_key", id, userData.Id).Scan(&blobKey)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ErrCapeNotFound
+		}
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE userstable SET active_cape_id = NULL, updated_at = now() WHERE active_cape_id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if err := m.releaseTexture(tx, blobKey); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetActiveCape makes one of the user's capes the one worn in game
+func (m *AppContext) SetActiveCape(userData *models.UserData, id int) (*models.CapeData, error) {
+	capeData, err := m.scanCape(m.DB.QueryRow(`UPDATE userstable SET active_cape_id = cape_id, updated_at = now()
+        FROM capestable WHERE user_id = $1 AND cape_id = $2 AND owner_id = user_id
+        RETURNING `+capeColumns, userData.Id, id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrCapeNotFound
+		}
+		return nil, err
+	}
+
+	return capeData, nil
+}
+
+// ClearActiveCape takes the user's cape off, capes are optional in game
+func (m *AppContext) ClearActiveCape(userData *models.UserData) error {
+	_, err := m.DB.Exec("UPDATE userstable SET active_cape_id = NULL, updated_at = now() WHERE user_id = $1", userData.Id)
+	return err
+}
+
+// GetActiveCape returns the cape a user wears in game
+func (m *AppContext) GetActiveCape(login string) (*models.CapeData, error) {
+	capeData, err := m.scanCape(m.DB.QueryRow("SELECT "+capeColumns+" FROM capestable WHERE cape_id = (SELECT active_cape_id FROM userstable WHERE login = $1)", login))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrActiveCapeNotSet
+		}
+		return nil, err
+	}
+
+	return capeData, nil
+}
+
+const capeColumns = "cape_id, cape_name, blob_key"
+
+// scanCape reads a capestable row selected with capeColumns
+func (m *AppContext) scanCape(row rowScanner) (*models.CapeData, error) {
+	var cape models.CapeData
+	var blobKey string
+
+	if err := row.Scan(&cape.Id, &cape.Name, &blobKey); err != nil {
+		return nil, err
+	}
+
+	cape.Texture = m.textureURL(blobKey)
+	cape.Hash = blobKey
+
+	return &cape, nil
+}
@@ -2,12 +2,18 @@ package database
 
 import (
 	"SkinRest/config"
+	"SkinRest/internal/mojang"
+	"SkinRest/internal/render"
+	"SkinRest/internal/storage"
+	"SkinRest/internal/texture"
+	"SkinRest/internal/yggdrasil"
 	"SkinRest/pkg/models"
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
@@ -15,18 +21,65 @@ import (
 
 type ApiHandler interface {
 	CreateNewUser(user *models.User) error
-	UpdateUserToken(user *models.User) (string, error)
 	GetInfoUser(user *models.User) (*models.UserData, error)
-	GetUserFromToken(token string) (*models.UserData, error)
-	AddNewSkin(userData *models.UserData, skin *models.Skin) (*models.SkinData, error)
+	GetSessionUser(sessionId int, userId int) (*models.UserData, error)
+	CreateSession(userData *models.UserData, client *models.SessionClient) (*models.TokenPair, error)
+	RefreshSession(refreshToken string, client *models.SessionClient) (*models.TokenPair, error)
+	GetUserSessions(userData *models.UserData, currentId int) ([]models.Session, error)
+	RevokeUserSession(userData *models.UserData, sessionId int, claims *AccessClaims) error
+	EndSession(userData *models.UserData, claims *AccessClaims) error
+	EndAllSessions(userData *models.UserData, claims *AccessClaims) error
+	IsTokenRevoked(jti string) (bool, error)
+	PruneRevokedTokens() (int64, error)
+	CreateApiKey(userData *models.UserData, apiKey *models.NewApiKey) (*models.ApiKeyCreated, error)
+	GetUserApiKeys(userData *models.UserData) ([]models.ApiKey, error)
+	DeleteUserApiKey(userData *models.UserData, id int) error
+	GetApiKeyUser(key string) (*models.UserData, []string, error)
+	ChangeUserLogin(userData *models.UserData, login string) error
+	GetRenamedLogin(login string) (string, error)
+	GetLoginHistory(userData *models.UserData) ([]models.LoginHistoryEntry, error)
+	SetUserRole(login string, role string) (*models.UserData, error)
+	BootstrapAdmin(userData *models.UserData) error
+	AddNewSkin(userData *models.UserData, skin *models.Skin, texture *models.SkinTexture) (*models.SkinData, error)
 	GetUserSkins(userData *models.UserData) ([]models.SkinData, error)
+	GetUserSkinsPage(userData *models.UserData, query *models.SkinQuery) (*models.SkinPage, error)
 	GetUserSkin(userData *models.UserData, id int) (*models.SkinData, error)
+	UpdateUserSkin(userData *models.UserData, id int, skin *models.Skin, texture *models.SkinTexture, version int) (*models.SkinData, error)
 	DeleteUserSkin(userData *models.UserData, id int) error
+	GetSkinTexture(userData *models.UserData, id int) ([]byte, error)
+	SetSkinTexture(userData *models.UserData, id int, texture *models.SkinTexture) (*models.SkinData, error)
+	GetTexture(key string) ([]byte, error)
+	SetActiveSkin(userData *models.UserData, id int) (*models.SkinData, error)
+	ClearActiveSkin(userData *models.UserData) error
+	GetActiveSkin(login string) (*models.SkinData, error)
+	AddNewCape(userData *models.UserData, cape *models.Cape, data []byte) (*models.CapeData, error)
+	GetUserCapes(userData *models.UserData) ([]models.CapeData, error)
+	GetUserCape(userData *models.UserData, id int) (*models.CapeData, error)
+	DeleteUserCape(userData *models.UserData, id int) error
+	SetActiveCape(userData *models.UserData, id int) (*models.CapeData, error)
+	ClearActiveCape(userData *models.UserData) error
+	GetActiveCape(login string) (*models.CapeData, error)
+	GetNicknameSkins() ([]models.SkinData, error)
+	ResyncSkin(id int, previousKey string, texture *models.SkinTexture, skinType string) (bool, error)
+	GetSkinHistory(userData *models.UserData, id int) ([]models.SkinHistoryEntry, error)
+	GetSkinVersions(userData *models.UserData, id int) ([]models.SkinVersion, error)
+	GetSkinVersion(userData *models.UserData, id int, version int) (*models.SkinVersion, error)
+	RestoreSkinVersion(userData *models.UserData, id int, version int) (*models.SkinData, error)
+	GetProfileByUUID(uuid string) (*models.Profile, error)
+	GetProfileByName(name string) (*models.Profile, error)
+	GetProfilesByNames(names []string) ([]models.Profile, error)
 }
 
 type AppContext struct {
-	DB     *sql.DB
-	Logger *zap.Logger
+	DB      *sql.DB
+	Logger  *zap.Logger
+	Storage storage.BlobStore    // skin texture bytes, referenced by blob key
+	BaseURL string               // public server address used in texture links
+	Mojang  *mojang.Client       // resolves nickname skin sources
+	Fetcher *texture.Fetcher     // downloads URL skin sources
+	Renders *render.Cache        // rendered previews keyed by texture hash
+	Sources *render.SourceHashes // texture hashes of skin sources that are not stored
+	Signer  *yggdrasil.Signer    // signs Yggdrasil textures properties
 }
 
 func New() *sql.DB {
@@ -35,38 +88,184 @@ func New() *sql.DB {
 	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s", cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.Name, cfg.Database.SSL) // set database connection string
 	fmt.Println(connStr)
 
-	db, err := sql.Open(cfg.Database.Driver, connStr)
+	db, err := Open(cfg.Database.Driver, connStr)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	return db
+}
+
+// Open connects to the database at connStr and creates the tables it is missing
+func Open(driver, connStr string) (*sql.DB, error) {
+	db, err := sql.Open(driver, connStr)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := db.Ping(); err != nil {
-		log.Fatal(err)
+		db.Close()
+		return nil, err
+	}
+
+	if err := createTables(db); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS public.userstable (
+	return db, nil
+}
+
+// createTables creates the tables and indexes the application uses, if they do not exist yet
+func createTables(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS public.userstable (
         user_id SERIAL PRIMARY KEY,
         login VARCHAR(20) NOT NULL,
         password VARCHAR(255) NOT NULL,
-        token VARCHAR(255) NOT NULL,
-        CONSTRAINT userstable_login_key UNIQUE (login)
+        user_uuid CHAR(32) NOT NULL,
+        role VARCHAR(16) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin')),
+        active_skin_id INT,
+        active_cape_id INT,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        CONSTRAINT userstable_login_key UNIQUE (login),
+        CONSTRAINT userstable_user_uuid_key UNIQUE (user_uuid)
     )`)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS public.skinstable (
         skin_id SERIAL PRIMARY KEY,
-		owner_name VARCHAR(20) NOT NULL,
+        owner_id INT NOT NULL REFERENCES userstable (user_id) ON DELETE CASCADE,
         skin_name VARCHAR(30) NOT NULL,
         skin_type VARCHAR(10) NOT NULL,
-        skin_src VARCHAR(255) NOT NULL
+        skin_src VARCHAR(255) NOT NULL,
+        blob_key VARCHAR(255) NOT NULL DEFAULT '',
+        original_blob_key VARCHAR(255) NOT NULL DEFAULT '',
+        source_url VARCHAR(2048) NOT NULL DEFAULT '',
+        version INT NOT NULL DEFAULT 1,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
     )`)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	return db
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS public.skinhistorytable (
+        history_id SERIAL PRIMARY KEY,
+        skin_id INT NOT NULL REFERENCES skinstable (skin_id) ON DELETE CASCADE,
+        previous_blob_key VARCHAR(255) NOT NULL DEFAULT '',
+        blob_key VARCHAR(255) NOT NULL,
+        skin_type VARCHAR(10) NOT NULL,
+        source_url VARCHAR(2048) NOT NULL DEFAULT '',
+        changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+    )`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS public.skinversionstable (
+        skin_id INT NOT NULL REFERENCES skinstable (skin_id) ON DELETE CASCADE,
+        version INT NOT NULL,
+        skin_name VARCHAR(30) NOT NULL,
+        skin_type VARCHAR(10) NOT NULL,
+        skin_src VARCHAR(255) NOT NULL,
+        blob_key VARCHAR(255) NOT NULL DEFAULT '',
+        original_blob_key VARCHAR(255) NOT NULL DEFAULT '',
+        source_url VARCHAR(2048) NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        PRIMARY KEY (skin_id, version)
+    )`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS public.capestable (
+        cape_id SERIAL PRIMARY KEY,
+        owner_id INT NOT NULL REFERENCES userstable (user_id) ON DELETE CASCADE,
+        cape_name VARCHAR(30) NOT NULL,
+        blob_key VARCHAR(255) NOT NULL
+    )`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS public.sessionstable (
+        session_id SERIAL PRIMARY KEY,
+        user_id INT NOT NULL REFERENCES userstable (user_id) ON DELETE CASCADE,
+        device_label VARCHAR(64) NOT NULL DEFAULT '',
+        user_agent VARCHAR(512) NOT NULL DEFAULT '',
+        ip VARCHAR(45) NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        revoked_at TIMESTAMPTZ
+    )`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS public.refreshtokenstable (
+        token_hash CHAR(64) PRIMARY KEY,
+        session_id INT NOT NULL REFERENCES sessionstable (session_id) ON DELETE CASCADE,
+        expires_at TIMESTAMPTZ NOT NULL,
+        used_at TIMESTAMPTZ
+    )`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS public.revokedtokenstable (
+        jti CHAR(32) PRIMARY KEY,
+        expires_at TIMESTAMPTZ NOT NULL
+    )`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS public.apikeystable (
+        key_id SERIAL PRIMARY KEY,
+        user_id INT NOT NULL REFERENCES userstable (user_id) ON DELETE CASCADE,
+        key_name VARCHAR(64) NOT NULL,
+        key_hash CHAR(64) NOT NULL UNIQUE,
+        key_prefix VARCHAR(16) NOT NULL,
+        scopes TEXT[] NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        last_used_at TIMESTAMPTZ
+    )`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS public.loginhistorytable (
+        history_id SERIAL PRIMARY KEY,
+        user_id INT NOT NULL REFERENCES userstable (user_id) ON DELETE CASCADE,
+        login VARCHAR(20) NOT NULL,
+        changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+    )`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS loginhistorytable_login_idx ON public.loginhistorytable (login);
+        CREATE INDEX IF NOT EXISTS refreshtokenstable_session_id_idx ON public.refreshtokenstable (session_id);
+        CREATE INDEX IF NOT EXISTS sessionstable_user_id_idx ON public.sessionstable (user_id);
+        CREATE INDEX IF NOT EXISTS apikeystable_user_id_idx ON public.apikeystable (user_id);
+        CREATE INDEX IF NOT EXISTS skinstable_owner_id_idx ON public.skinstable (owner_id);
+        CREATE INDEX IF NOT EXISTS capestable_owner_id_idx ON public.capestable (owner_id)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS public.texturestable (
+        blob_key VARCHAR(255) PRIMARY KEY,
+        ref_count INT NOT NULL DEFAULT 0
+    )`)
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (m *AppContext) CreateNewUser(user *models.User) error {
@@ -80,16 +279,22 @@ func (m *AppContext) CreateNewUser(user *models.User) error {
 		return models.ErrAlrRegistered
 	}
 
-	token := GenerateNewToken(user)
-
 	passwordHash, err := GetPasswordHash(user.Password)
 	if err != nil {
 		return err
 	}
 
-	_, err = m.DB.Exec("INSERT INTO userstable (login, password, token) VALUES ($1, $2, $3)", user.Login, passwordHash, token)
+	uuid, err := newUserUUID(user.Login, m.uuidTaken)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.Exec("INSERT INTO userstable (login, password, user_uuid, created_at, updated_at) VALUES ($1, $2, $3, now(), now())", user.Login, passwordHash, uuid)
 
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation, lost a race for the login
+			return models.ErrAlrRegistered
+		}
 		return err
 	}
 
@@ -97,76 +302,95 @@ func (m *AppContext) CreateNewUser(user *models.User) error {
 
 }
 
-func (m *AppContext) UpdateUserToken(user *models.User) (string, error) {
-	newToken := GenerateNewToken(user)
-	res, err := m.DB.Exec("UPDATE userstable SET token = $1 WHERE login = $2", newToken, user.Login)
+// newUserUUID picks the profile UUID of a new user: the offline-mode UUID of
+// the login, unless a user who renamed away from that login still holds it
+func newUserUUID(login string, taken func(uuid string) (bool, error)) (string, error) {
+	uuid := yggdrasil.OfflineUUID(login)
 
+	isTaken, err := taken(uuid)
 	if err != nil {
 		return "", err
 	}
 
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return "", err
+	if isTaken {
+		return yggdrasil.RandomUUID()
 	}
 
-	if rowsAffected == 0 {
-		return "", models.ErrUserNotFound
-	}
+	return uuid, nil
+}
 
-	return newToken, nil
+func (m *AppContext) uuidTaken(uuid string) (bool, error) {
+	var taken bool
+	err := m.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM userstable WHERE user_uuid = $1)", uuid).Scan(&taken)
+	return taken, err
 }
 
 func (m *AppContext) GetInfoUser(user *models.User) (*models.UserData, error) {
-	var userData models.UserData
-
-	err := m.DB.QueryRow("SELECT user_id, login, password, token FROM userstable WHERE login = $1", user.Login).Scan(&userData.Id, &userData.Login, &userData.Password, &userData.Token)
+	userData, err := scanUser(m.DB.QueryRow("SELECT "+userColumns+" FROM userstable WHERE login = $1", user.Login))
 
 	if err != nil {
-
-		if err == sql.ErrNoRows {
-			return nil, models.ErrUserNotFound
-		}
 		return nil, err
 	}
 
 	if ValidatePasswordHash(user.Password, userData.Password) {
-		return &userData, nil
+		return userData, nil
 	}
 
 	return nil, models.ErrUserNotFound
 
 }
 
-func (m *AppContext) GetUserFromToken(token string) (*models.UserData, error) {
-	var userData models.UserData
-
-	err := m.DB.QueryRow("SELECT user_id, login, password, token FROM userstable WHERE token = $1", token).Scan(&userData.Id, &userData.Login, &userData.Password, &userData.Token)
+func (m *AppContext) AddNewSkin(userData *models.UserData, skin *models.Skin, texture *models.SkinTexture) (*models.SkinData, error) {
+	var skin_id int
+	var blobKey, originalBlobKey, sourceURL string
+	var createdAt time.Time
 
+	tx, err := m.beginTextureTx()
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, models.ErrUserNotFound
-		}
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	return &userData, nil
-}
+	if texture != nil {
+		blobKey, originalBlobKey, err = m.acquireSkinTexture(tx, texture)
+		if err != nil {
+			return nil, err
+		}
+		sourceURL = texture.SourceURL
+	}
 
-func (m *AppContext) AddNewSkin(userData *models.UserData, skin *models.Skin) (*models.SkinData, error) {
-	var skin_id int
+	err = tx.QueryRow("INSERT INTO skinstable (owner_id, skin_name, skin_type, skin_src, blob_key, original_blob_key, source_url, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, now(), now()) RETURNING skin_id, created_at", userData.Id, skin.Name, skin.Type, skin.Src, blobKey, originalBlobKey, sourceURL).Scan(&skin_id, &createdAt)
 
-	err := m.DB.QueryRow("INSERT INTO skinstable (owner_name, skin_name, skin_type, skin_src) VALUES ($1, $2, $3, $4) RETURNING skin_id", userData.Login, skin.Name, skin.Type, skin.Src).Scan(&skin_id)
+	if err != nil {
+		return nil, err
+	}
 
+	if err := m.snapshotVersion(tx.Tx, skin_id); err != nil {
+		return nil, err
+	}
+
+	// The first skin a user adds is worn right away
+	_, err = tx.Exec("UPDATE userstable SET active_skin_id = $1, updated_at = now() WHERE user_id = $2 AND active_skin_id IS NULL", skin_id, userData.Id)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	skinData := &models.SkinData{
-		Id:   skin_id,
-		Name: skin.Name,
-		Type: skin.Type,
-		Src:  skin.Src,
+		Id:              skin_id,
+		Name:            skin.Name,
+		Type:            skin.Type,
+		Src:             skin.Src,
+		Texture:         m.textureURL(blobKey),
+		OriginalTexture: m.textureURL(originalBlobKey),
+		SourceURL:       sourceURL,
+		Version:         1,
+		CreatedAt:       createdAt,
+		UpdatedAt:       createdAt,
+		Hash:            blobKey,
 	}
 
 	return skinData, nil
@@ -175,7 +399,7 @@ func (m *AppContext) AddNewSkin(userData *models.UserData, skin *models.Skin) (*
 func (m *AppContext) GetUserSkins(userData *models.UserData) ([]models.SkinData, error) {
 	var skins []models.SkinData
 
-	rows, err := m.DB.Query("SELECT skin_id, skin_name, skin_type, skin_src FROM skinstable WHERE owner_name = $1", userData.Login)
+	rows, err := m.DB.Query("SELECT "+skinColumns+" FROM skinstable WHERE owner_id = $1", userData.Id)
 
 	if err != nil {
 		return nil, err
@@ -183,11 +407,11 @@ func (m *AppContext) GetUserSkins(userData *models.UserData) ([]models.SkinData,
 	defer rows.Close()
 
 	for rows.Next() {
-		var skin models.SkinData
-		if err := rows.Scan(&skin.Id, &skin.Name, &skin.Type, &skin.Src); err != nil {
+		skin, err := m.scanSkin(rows)
+		if err != nil {
 			return nil, err
 		}
-		skins = append(skins, skin)
+		skins = append(skins, *skin)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -198,10 +422,30 @@ func (m *AppContext) GetUserSkins(userData *models.UserData) ([]models.SkinData,
 }
 
 func (m *AppContext) GetUserSkin(userData *models.UserData, id int) (*models.SkinData, error) {
-	var skinData models.SkinData
+	skinData, err := m.scanSkin(m.DB.QueryRow("SELECT "+skinColumns+" FROM skinstable WHERE skin_id = $1 AND owner_id = $2", id, userData.Id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrSkinNotFound
+		}
+		return nil, err
+	}
+
+	return skinData, nil
+
+}
 
-	err := m.DB.QueryRow("SELECT skin_id, skin_name, skin_type, skin_src FROM skinstable WHERE skin_id = $1", id).Scan(&skinData.Id, &skinData.Name, &skinData.Type, &skinData.Src)
+// UpdateUserSkin replaces the name, type and source of one of the user's skins,
+// and its texture unless texture is nil. The update only applies while the
+// skin is still at the given version, and records a new version if anything changed.
+func (m *AppContext) UpdateUserSkin(userData *models.UserData, id int, skin *models.Skin, texture *models.SkinTexture, version int) (*models.SkinData, error) {
+	tx, err := m.beginTextureTx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
+	current, err := m.scanSkin(tx.QueryRow("SELECT "+skinColumns+" FROM skinstable WHERE skin_id = $1 AND owner_id = $2 FOR UPDATE", id, userData.Id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, models.ErrSkinNotFound
@@ -209,25 +453,198 @@ func (m *AppContext) GetUserSkin(userData *models.UserData, id int) (*models.Ski
 		return nil, err
 	}
 
-	return &skinData, nil
+	if current.Version != version {
+		return nil, models.ErrSkinVersionConflict
+	}
+
+	if texture == nil && current.Name == skin.Name && current.Type == skin.Type && current.Src == skin.Src {
+		return current, nil // nothing to record
+	}
+
+	_, err = tx.Exec("UPDATE skinstable SET skin_name = $1, skin_type = $2, skin_src = $3, version = version + 1, updated_at = now() WHERE skin_id = $4", skin.Name, skin.Type, skin.Src, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if texture != nil {
+		var oldBlobKey, oldOriginalBlobKey string
+		if err := tx.QueryRow("SELECT blob_key, original_blob_key FROM skinstable WHERE skin_id = $1", id).Scan(&oldBlobKey, &oldOriginalBlobKey); err != nil {
+			return nil, err
+		}
+
+		// take the new references before dropping the old ones, they may share blobs
+		blobKey, originalBlobKey, err := m.acquireSkinTexture(tx, texture)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.Exec("UPDATE skinstable SET blob_key = $1, original_blob_key = $2, source_url = $3 WHERE skin_id = $4", blobKey, originalBlobKey, texture.SourceURL, id); err != nil {
+			return nil, err
+		}
+
+		if err := m.releaseTexture(tx, oldBlobKey); err != nil {
+			return nil, err
+		}
+		if err := m.releaseTexture(tx, oldOriginalBlobKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := m.snapshotVersion(tx.Tx, id); err != nil {
+		return nil, err
+	}
+
+	skinData, err := m.scanSkin(tx.QueryRow("SELECT "+skinColumns+" FROM skinstable WHERE skin_id = $1", id))
+	if err != nil {
+		return nil, err
+	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return skinData, nil
 }
 
 func (m *AppContext) DeleteUserSkin(userData *models.UserData, id int) error {
-	res, err := m.DB.Exec("DELETE FROM skinstable WHERE skin_id = $1", id)
+	var blobKey, originalBlobKey string
 
+	tx, err := m.beginTextureTx()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := res.RowsAffected()
+	// History entries hold references to their textures too
+	historyKeys, err := deleteSkinHistory(tx.Tx, userData, id)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return models.ErrSkinNotFound
+	versionKeys, err := deleteSkinVersions(tx.Tx, userData, id)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	err = tx.QueryRow("DELETE FROM skinstable WHERE skin_id = $1 AND owner_id = $2 RETURNING blob_key, original_blob_key", id, userData.Id).Scan(&blobKey, &originalBlobKey)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ErrSkinNotFound
+		}
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE userstable SET active_skin_id = NULL, updated_at = now() WHERE active_skin_id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// Remove the textures only if no other skin points at them
+	if err := m.releaseTexture(tx, blobKey); err != nil {
+		return err
+	}
+	if err := m.releaseTexture(tx, originalBlobKey); err != nil {
+		return err
+	}
+	for _, key := range append(historyKeys, versionKeys...) {
+		if err := m.releaseTexture(tx, key); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetActiveSkin makes one of the user's skins the one worn in game
+func (m *AppContext) SetActiveSkin(userData *models.UserData, id int) (*models.SkinData, error) {
+	// both tables have timestamps, so the skin is selected apart from the update
+	skinData, err := m.scanSkin(m.DB.QueryRow(`WITH selected AS (
+            UPDATE userstable SET active_skin_id = skin_id, updated_at = now()
+            FROM skinstable WHERE user_id = $1 AND skin_id = $2 AND owner_id = user_id
+            RETURNING active_skin_id
+        )
+        SELECT `+skinColumns+` FROM skinstable WHERE skin_id = (SELECT active_skin_id FROM selected)`, userData.Id, id))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrSkinNotFound
+		}
+		return nil, err
+	}
+
+	return skinData, nil
+}
+
+// ClearActiveSkin takes the user's skin off, game clients then show their default skin
+func (m *AppContext) ClearActiveSkin(userData *models.UserData) error {
+	_, err := m.DB.Exec("UPDATE userstable SET active_skin_id = NULL, updated_at = now() WHERE user_id = $1", userData.Id)
+	return err
+}
+
+// GetActiveSkin returns the skin a user wears in game
+func (m *AppContext) GetActiveSkin(login string) (*models.SkinData, error) {
+	var activeSkin *int
+
+	err := m.DB.QueryRow("SELECT active_skin_id FROM userstable WHERE login = $1", login).Scan(&activeSkin)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if activeSkin == nil {
+		return nil, models.ErrActiveSkinNotSet
+	}
+
+	skinData, err := m.scanSkin(m.DB.QueryRow("SELECT "+skinColumns+" FROM skinstable WHERE skin_id = $1", *activeSkin))
+	if err != nil {
+		if err == sql.ErrNoRows { // deleted in the meantime
+			return nil, models.ErrActiveSkinNotSet
+		}
+		return nil, err
+	}
+
+	return skinData, nil
+}
+
+const skinColumns = "skin_id, skin_name, skin_type, skin_src, blob_key, original_blob_key, source_url, version, created_at, updated_at"
+
+const userColumns = "user_id, login, password, user_uuid, role, active_skin_id, active_cape_id, created_at, updated_at"
+
+// scanUser reads a userstable row selected with userColumns
+func scanUser(row rowScanner) (*models.UserData, error) {
+	var userData models.UserData
+
+	err := row.Scan(&userData.Id, &userData.Login, &userData.Password, &userData.UUID, &userData.Role, &userData.ActiveSkin, &userData.ActiveCape, &userData.CreatedAt, &userData.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &userData, nil
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSkin reads a skinstable row selected with skinColumns
+func (m *AppContext) scanSkin(row rowScanner) (*models.SkinData, error) {
+	var skin models.SkinData
+	var blobKey, originalBlobKey string
+
+	if err := row.Scan(&skin.Id, &skin.Name, &skin.Type, &skin.Src, &blobKey, &originalBlobKey, &skin.SourceURL, &skin.Version, &skin.CreatedAt, &skin.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	skin.Texture = m.textureURL(blobKey)
+	skin.OriginalTexture = m.textureURL(originalBlobKey)
+	skin.Hash = blobKey
+
+	return &skin, nil
 }
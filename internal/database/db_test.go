@@ -0,0 +1,252 @@
+package database
+
+import (
+	"SkinRest/internal/render"
+	"SkinRest/internal/storage"
+	"SkinRest/internal/yggdrasil"
+	"SkinRest/pkg/models"
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// testContext connects to the PostgreSQL database in TEST_DATABASE_URL and
+// creates the tables. Tests that need a database are skipped without one.
+func testContext(t *testing.T) *AppContext {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	// the config is read again by token and session helpers
+	t.Setenv("DATABASE_USER", "test")
+	t.Setenv("DATABASE_PASSWORD", "test")
+	t.Setenv("DATABASE_NAME", "test")
+	t.Setenv("AUTH_JWT_SECRET", "test-secret")
+
+	db, err := Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return &AppContext{
+		DB:      db,
+		Logger:  zap.NewNop(),
+		Storage: storage.NewLocalStore(t.TempDir()),
+		Renders: render.NewCache(16, ""),
+	}
+}
+
+// testUser registers a user with a unique login, removed again with its skins after the test
+func testUser(t *testing.T, m *AppContext) *models.UserData {
+	t.Helper()
+
+	user := &models.User{Login: fmt.Sprintf("t%d", time.Now().UnixNano()%1e15), Password: "password"}
+	if err := m.CreateNewUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	userData, err := m.GetInfoUser(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.DB.Exec("DELETE FROM userstable WHERE user_id = $1", userData.Id) })
+
+	return userData
+}
+
+// testTexture is a classic skin with random pixels, so no two tests share a blob
+func testTexture(t *testing.T) *models.SkinTexture {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	if _, err := rand.Read(img.Pix); err != nil {
+		t.Fatal(err)
+	}
+	// opaque but for the corner, which keeps the alpha channel in the PNG
+	img.Pix[3] = 0
+	for i := 7; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return &models.SkinTexture{Data: buf.Bytes()}
+}
+
+// refCount is the number of references texturestable holds on key, 0 if it has no row
+func refCount(t *testing.T, m *AppContext, key string) int {
+	t.Helper()
+
+	var count int
+	err := m.DB.QueryRow("SELECT ref_count FROM texturestable WHERE blob_key = $1", key).Scan(&count)
+	if err != nil && err != sql.ErrNoRows {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// blobStored reports whether storage holds the blob of key
+func blobStored(t *testing.T, m *AppContext, key string) bool {
+	t.Helper()
+
+	_, err := m.Storage.Get(key)
+	if err == models.ErrBlobNotFound {
+		return false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return true
+}
+
+func TestNewUserUUIDAfterRename(t *testing.T) {
+	// "bob" was renamed to "alice" and kept the offline UUID of "bob"
+	held := map[string]bool{yggdrasil.OfflineUUID("bob"): true}
+	taken := func(uuid string) (bool, error) { return held[uuid], nil }
+
+	uuid, err := newUserUUID("bob", taken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, yggdrasil.OfflineUUID("bob"), uuid)
+	assert.Len(t, uuid, 32)
+
+	uuid, err = newUserUUID("carol", taken)
+	assert.NoError(t, err)
+	assert.Equal(t, yggdrasil.OfflineUUID("carol"), uuid)
+}
+
+func TestUpdateUserSkinVersionConflict(t *testing.T) {
+	m := testContext(t)
+	user := testUser(t, m)
+
+	skin, err := m.AddNewSkin(user, &models.Skin{Name: "First", Type: models.SkinTypeClassic}, testTexture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := m.UpdateUserSkin(user, skin.Id, &models.Skin{Name: "Second", Type: models.SkinTypeClassic}, nil, skin.Version)
+	assert.NoError(t, err)
+	assert.Equal(t, skin.Version+1, updated.Version)
+
+	// a writer still holding the first version loses
+	_, err = m.UpdateUserSkin(user, skin.Id, &models.Skin{Name: "Third", Type: models.SkinTypeClassic}, nil, skin.Version)
+	assert.Equal(t, models.ErrSkinVersionConflict, err)
+
+	current, err := m.GetUserSkin(user, skin.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Second", current.Name)
+	assert.Equal(t, updated.Version, current.Version)
+}
+
+func TestUpdateUserSkinNameKeepsTexture(t *testing.T) {
+	m := testContext(t)
+	user := testUser(t, m)
+
+	skin, err := m.AddNewSkin(user, &models.Skin{Name: "Before", Type: models.SkinTypeSlim}, testTexture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// what PatchSkin merges for a body holding only the name
+	updated, err := m.UpdateUserSkin(user, skin.Id, &models.Skin{Name: "After", Type: skin.Type, Src: skin.Src}, nil, skin.Version)
+	assert.NoError(t, err)
+	assert.Equal(t, "After", updated.Name)
+	assert.Equal(t, skin.Hash, updated.Hash)
+	assert.Equal(t, models.SkinTypeSlim, updated.Type)
+	assert.Equal(t, 1, refCount(t, m, skin.Hash))
+	assert.True(t, blobStored(t, m, skin.Hash))
+}
+
+func TestSkinTimestamps(t *testing.T) {
+	m := testContext(t)
+	user := testUser(t, m)
+	assert.False(t, user.CreatedAt.IsZero())
+	assert.True(t, user.UpdatedAt.Equal(user.CreatedAt))
+
+	skin, err := m.AddNewSkin(user, &models.Skin{Name: "Dated", Type: models.SkinTypeClassic}, testTexture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, skin.CreatedAt.IsZero())
+	assert.True(t, skin.UpdatedAt.Equal(skin.CreatedAt))
+
+	// the first skin is worn at once, which changes the user
+	user, err = m.GetInfoUser(&models.User{Login: user.Login, Password: "password"})
+	assert.NoError(t, err)
+	assert.True(t, user.UpdatedAt.After(user.CreatedAt))
+
+	stored, err := m.GetUserSkin(user, skin.Id)
+	assert.NoError(t, err)
+	assert.True(t, stored.CreatedAt.Equal(skin.CreatedAt))
+
+	// saving a skin unchanged is not a modification
+	same, err := m.UpdateUserSkin(user, skin.Id, &models.Skin{Name: "Dated", Type: models.SkinTypeClassic}, nil, skin.Version)
+	assert.NoError(t, err)
+	assert.True(t, same.UpdatedAt.Equal(skin.UpdatedAt))
+
+	time.Sleep(10 * time.Millisecond)
+	renamed, err := m.UpdateUserSkin(user, skin.Id, &models.Skin{Name: "Renamed", Type: models.SkinTypeClassic}, nil, skin.Version)
+	assert.NoError(t, err)
+	assert.True(t, renamed.CreatedAt.Equal(skin.CreatedAt))
+	assert.True(t, renamed.UpdatedAt.After(skin.UpdatedAt))
+}
+
+func TestSkinOwnership(t *testing.T) {
+	m := testContext(t)
+	alice := testUser(t, m)
+	mallory := testUser(t, m)
+
+	skin, err := m.AddNewSkin(alice, &models.Skin{Name: "Hers", Type: models.SkinTypeClassic}, testTexture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every skin query is limited to the skins of the user by owner_id
+	_, err = m.GetUserSkin(mallory, skin.Id)
+	assert.Equal(t, models.ErrSkinNotFound, err)
+
+	_, err = m.UpdateUserSkin(mallory, skin.Id, &models.Skin{Name: "Mine", Type: models.SkinTypeClassic}, nil, skin.Version)
+	assert.Equal(t, models.ErrSkinNotFound, err)
+
+	_, err = m.SetActiveSkin(mallory, skin.Id)
+	assert.Equal(t, models.ErrSkinNotFound, err)
+
+	_, err = m.GetSkinVersions(mallory, skin.Id)
+	assert.Equal(t, models.ErrSkinNotFound, err)
+
+	_, err = m.RestoreSkinVersion(mallory, skin.Id, 1)
+	assert.Equal(t, models.ErrSkinNotFound, err)
+
+	assert.Equal(t, models.ErrSkinNotFound, m.DeleteUserSkin(mallory, skin.Id))
+
+	skins, err := m.GetUserSkins(mallory)
+	assert.NoError(t, err)
+	assert.Empty(t, skins)
+
+	stored, err := m.GetUserSkin(alice, skin.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hers", stored.Name)
+	assert.Equal(t, 1, stored.Version)
+
+	// skins go with their owner
+	_, err = m.DB.Exec("DELETE FROM userstable WHERE user_id = $1", alice.Id)
+	assert.NoError(t, err)
+
+	var left int
+	assert.NoError(t, m.DB.QueryRow("SELECT COUNT(*) FROM skinstable WHERE skin_id = $1", skin.Id).Scan(&left))
+	assert.Equal(t, 0, left)
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"SkinRest/pkg/models"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// Every login a user gives up is kept in loginhistorytable, so that public
+// lookups by an old name can be sent on to the user's current login. A name
+// that is taken again belongs to its new owner, its history is not consulted.
+
+// ChangeUserLogin renames the user, recording the old login in the user's name history
+func (m *AppContext) ChangeUserLogin(userData *models.UserData, login string) error {
+	if login == userData.Login {
+		return models.ErrSameLogin
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE userstable SET login = $1, updated_at = now() WHERE user_id = $2", login, userData.Id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			return models.ErrLoginTaken
+		}
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+
+	if _, err := tx.Exec("INSERT INTO loginhistorytable (user_id, login) VALUES ($1, $2)", userData.Id, userData.Login); err != nil {
+		return err
+	}
+
+	// Taking back an old name ends its redirect
+	if _, err := tx.Exec("DELETE FROM loginhistorytable WHERE user_id = $1 AND login = $2", userData.Id, login); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetRenamedLogin returns the current login of the user who last gave up
+// login, or ErrUserNotFound if nobody did or someone holds it again
+func (m *AppContext) GetRenamedLogin(login string) (string, error) {
+	var current string
+
+	err := m.DB.QueryRow(`SELECT userstable.login FROM loginhistorytable
+        JOIN userstable ON userstable.user_id = loginhistorytable.user_id
+        WHERE loginhistorytable.login = $1 AND NOT EXISTS (SELECT 1 FROM userstable WHERE login = $1)
+        ORDER BY loginhistorytable.changed_at DESC LIMIT 1`, login).Scan(&current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", models.ErrUserNotFound
+		}
+		return "", err
+	}
+
+	return current, nil
+}
+
+// GetLoginHistory lists the logins the user has given up, most recent first
+func (m *AppContext) GetLoginHistory(userData *models.UserData) ([]models.LoginHistoryEntry, error) {
+	var history []models.LoginHistoryEntry
+
+	rows, err := m.DB.Query("SELECT login, changed_at FROM loginhistorytable WHERE user_id = $1 ORDER BY changed_at DESC", userData.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.LoginHistoryEntry
+		if err := rows.Scan(&entry.Login, &entry.ChangedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
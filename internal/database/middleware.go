@@ -3,7 +3,11 @@ package database
 import (
 	"SkinRest/config"
 	"SkinRest/pkg/models"
-	"log"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 
 	"time"
 
@@ -11,51 +15,89 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-func GenerateNewToken(user *models.User) string {
-	secret := config.GetConfig().Auth.JwtSecret
-
-	payload := &jwt.MapClaims{
-		"sub": user.Login,
-		"iat": time.Now().Unix(),
-		"exp": time.Now().Add(14 * 24 * time.Hour).Unix(), // 14 days expiration time
-	}
+// AccessClaims are the claims of an access token. The subject is the login
+// the token was issued to, users are identified by UserId since they can rename.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	UserId    int `json:"uid"`
+	SessionId int `json:"sid"` // session whose refresh token can renew the access token
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+// GenerateAccessToken issues a short-lived access token for a session of the user
+func GenerateAccessToken(userData *models.UserData, sessionId int) (string, error) {
+	cfg := config.GetConfig().Auth
+	now := time.Now()
 
-	tokenString, err := token.SignedString([]byte(secret))
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
 
-	if err != nil {
-		log.Fatal(err)
+	claims := &AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(jti), // lets a single token be revoked before it expires
+			Subject:   userData.Login,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(cfg.AccessTokenTTL)),
+		},
+		UserId:    userData.Id,
+		SessionId: sessionId,
 	}
 
-	return tokenString
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString([]byte(cfg.JwtSecret))
+}
 
+// ParseAccessToken verifies an access token and returns its claims
+func ParseAccessToken(tokenString string) (*AccessClaims, error) {
+	return parseAccessToken(tokenString, []byte(config.GetConfig().Auth.JwtSecret))
 }
 
-func CheckToken(tokenString string) error {
+func parseAccessToken(tokenString string, secret []byte) (*AccessClaims, error) {
+	var claims AccessClaims
 
-	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok { // check token signing method
 			return nil, models.ErrInvalidSigningMethod
 		}
-		return []byte(config.GetConfig().Auth.JwtSecret), nil
-	})
+		return secret, nil
+	}, jwt.WithExpirationRequired())
 
 	if err != nil {
-		return err
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, models.ErrTokenExpired
+		}
+		return nil, models.ErrInvalidToken
 	}
 
-	claims := token.Claims
-	expTime, err := claims.GetExpirationTime()
-	if err != nil {
-		return err
+	if !token.Valid || claims.ID == "" || claims.UserId < 1 || claims.SessionId < 1 {
+		return nil, models.ErrInvalidTokenClaims
 	}
 
-	if expTime.Before(time.Now()) { // error if token has expired
-		return models.ErrTokenExpired
+	return &claims, nil
+}
+
+// newRefreshToken returns an opaque refresh token and the hash it is stored as
+func newRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
 	}
 
-	return nil
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashSecret(token), nil
+}
+
+// hashSecret is how refresh tokens and API keys are stored, a leaked table cannot be replayed
+func hashSecret(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// accessTokenLifetime is the lifetime of access tokens in seconds, as reported to clients
+func accessTokenLifetime() int {
+	return int(config.GetConfig().Auth.AccessTokenTTL / time.Second)
 }
 
 func GetPasswordHash(password string) (string, error) {
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"SkinRest/pkg/models"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSecret = []byte("test-secret")
+
+func signClaims(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.Claims) string {
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func validClaims() *AccessClaims {
+	now := time.Now()
+	return &AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "0123456789abcdef",
+			Subject:   "john",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+		UserId:    1,
+		SessionId: 2,
+	}
+}
+
+func TestParseAccessToken(t *testing.T) {
+	claims, err := parseAccessToken(signClaims(t, jwt.SigningMethodHS256, testSecret, validClaims()), testSecret)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, claims.UserId)
+	assert.Equal(t, 2, claims.SessionId)
+	assert.Equal(t, "0123456789abcdef", claims.ID)
+}
+
+func TestParseAccessTokenRejects(t *testing.T) {
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	_, err := parseAccessToken(signClaims(t, jwt.SigningMethodHS256, testSecret, expired), testSecret)
+	assert.Equal(t, models.ErrTokenExpired, err)
+
+	noExpiry := validClaims()
+	noExpiry.ExpiresAt = nil
+	_, err = parseAccessToken(signClaims(t, jwt.SigningMethodHS256, testSecret, noExpiry), testSecret)
+	assert.Equal(t, models.ErrInvalidToken, err)
+
+	_, err = parseAccessToken(signClaims(t, jwt.SigningMethodHS256, []byte("other-secret"), validClaims()), testSecret)
+	assert.Equal(t, models.ErrInvalidToken, err)
+
+	// unsigned tokens and non-HMAC algorithms are refused before the key is used
+	_, err = parseAccessToken(signClaims(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims()), testSecret)
+	assert.Equal(t, models.ErrInvalidToken, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, err = parseAccessToken(signClaims(t, jwt.SigningMethodRS256, key, validClaims()), testSecret)
+	assert.Equal(t, models.ErrInvalidToken, err)
+
+	for name, mutate := range map[string]func(*AccessClaims){
+		"uid": func(c *AccessClaims) { c.UserId = 0 },
+		"sid": func(c *AccessClaims) { c.SessionId = 0 },
+		"jti": func(c *AccessClaims) { c.ID = "" },
+	} {
+		claims := validClaims()
+		mutate(claims)
+		_, err = parseAccessToken(signClaims(t, jwt.SigningMethodHS256, testSecret, claims), testSecret)
+		assert.Equal(t, models.ErrInvalidTokenClaims, err, "missing "+name)
+	}
+
+	_, err = parseAccessToken("not.a.token", testSecret)
+	assert.Equal(t, models.ErrInvalidToken, err)
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// migrationTx starts a transaction working in a schema of its own, with the
+// tables as they were before a migration created by setup. Everything is
+// rolled back after the test.
+func migrationTx(t *testing.T, m *AppContext, setup string) *sql.Tx {
+	t.Helper()
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+
+	for _, stmt := range []string{"CREATE SCHEMA migration_test", "SET LOCAL search_path TO migration_test", setup} {
+		if _, err := tx.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tx
+}
+
+// migrateUp runs the Up section of a goose migration in tx
+func migrateUp(t *testing.T, tx *sql.Tx, name string) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("..", "..", "migrations", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	up, _, found := strings.Cut(string(data), "-- +goose Down")
+	if !found {
+		t.Fatal("no Down section in " + name)
+	}
+
+	if _, err := tx.Exec(up); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTimestampsMigration(t *testing.T) {
+	m := testContext(t)
+	tx := migrationTx(t, m, `
+        CREATE TABLE userstable (user_id SERIAL PRIMARY KEY, login VARCHAR(20) NOT NULL);
+        CREATE TABLE skinstable (skin_id INT PRIMARY KEY);
+        CREATE TABLE skinversionstable (skin_id INT NOT NULL, created_at TIMESTAMPTZ NOT NULL);
+        INSERT INTO userstable (login) VALUES ('alice');
+        INSERT INTO skinstable (skin_id) VALUES (1), (2);
+        INSERT INTO skinversionstable (skin_id, created_at) VALUES
+            (1, '2024-10-01T10:00:00Z'), (1, '2024-10-05T10:00:00Z'), (1, '2024-10-03T10:00:00Z');`)
+
+	migrateUp(t, tx, "20241031090000_timestamps.sql")
+
+	// a skin with versions is dated by them
+	var createdAt, updatedAt time.Time
+	assert.NoError(t, tx.QueryRow("SELECT created_at, updated_at FROM skinstable WHERE skin_id = 1").Scan(&createdAt, &updatedAt))
+	assert.True(t, createdAt.Equal(time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC)), createdAt)
+	assert.True(t, updatedAt.Equal(time.Date(2024, 10, 5, 10, 0, 0, 0, time.UTC)), updatedAt)
+
+	// anything else gets the time of the migration
+	var migratedAt time.Time
+	assert.NoError(t, tx.QueryRow("SELECT now()").Scan(&migratedAt))
+	assert.NoError(t, tx.QueryRow("SELECT created_at, updated_at FROM skinstable WHERE skin_id = 2").Scan(&createdAt, &updatedAt))
+	assert.True(t, createdAt.Equal(migratedAt))
+	assert.True(t, updatedAt.Equal(migratedAt))
+
+	assert.NoError(t, tx.QueryRow("SELECT created_at, updated_at FROM userstable WHERE login = 'alice'").Scan(&createdAt, &updatedAt))
+	assert.True(t, createdAt.Equal(migratedAt))
+	assert.True(t, updatedAt.Equal(migratedAt))
+}
+
+func TestOwnerIdMigration(t *testing.T) {
+	m := testContext(t)
+	tx := migrationTx(t, m, `
+        CREATE TABLE userstable (user_id SERIAL PRIMARY KEY, login VARCHAR(20) NOT NULL);
+        CREATE TABLE skinstable (skin_id INT PRIMARY KEY, owner_name VARCHAR(20) NOT NULL,
+            blob_key VARCHAR(255) NOT NULL DEFAULT '', original_blob_key VARCHAR(255) NOT NULL DEFAULT '');
+        CREATE TABLE capestable (cape_id INT PRIMARY KEY, owner_name VARCHAR(20) NOT NULL, blob_key VARCHAR(255) NOT NULL);
+        CREATE TABLE skinversionstable (skin_id INT NOT NULL, blob_key VARCHAR(255) NOT NULL, original_blob_key VARCHAR(255) NOT NULL);
+        CREATE TABLE skinhistorytable (skin_id INT NOT NULL, blob_key VARCHAR(255) NOT NULL, previous_blob_key VARCHAR(255) NOT NULL);
+        CREATE TABLE texturestable (blob_key VARCHAR(255) PRIMARY KEY, ref_count INT NOT NULL DEFAULT 0);
+        INSERT INTO userstable (login) VALUES ('alice');
+        INSERT INTO skinstable (skin_id, owner_name, blob_key) VALUES (1, 'alice', 'shared'), (2, 'ghost', 'shared');
+        INSERT INTO skinversionstable (skin_id, blob_key, original_blob_key) VALUES (1, 'shared', ''), (2, 'shared', 'ghost-original');
+        INSERT INTO skinhistorytable (skin_id, blob_key, previous_blob_key) VALUES (2, 'shared', 'ghost-previous');
+        INSERT INTO capestable (cape_id, owner_name, blob_key) VALUES (1, 'alice', 'cape'), (2, 'ghost', 'cape');
+        INSERT INTO texturestable (blob_key, ref_count) VALUES ('shared', 5), ('ghost-original', 1), ('ghost-previous', 1), ('cape', 2);`)
+
+	migrateUp(t, tx, "20241101100000_owner_id.sql")
+
+	var aliceId int
+	assert.NoError(t, tx.QueryRow("SELECT user_id FROM userstable WHERE login = 'alice'").Scan(&aliceId))
+
+	// the skin and cape of alice now point at her row
+	var ownerId int
+	assert.NoError(t, tx.QueryRow("SELECT owner_id FROM skinstable WHERE skin_id = 1").Scan(&ownerId))
+	assert.Equal(t, aliceId, ownerId)
+	assert.NoError(t, tx.QueryRow("SELECT owner_id FROM capestable WHERE cape_id = 1").Scan(&ownerId))
+	assert.Equal(t, aliceId, ownerId)
+
+	// those of an owner who no longer exists are gone, with their references
+	var orphans int
+	assert.NoError(t, tx.QueryRow("SELECT (SELECT COUNT(*) FROM skinstable WHERE skin_id = 2) + (SELECT COUNT(*) FROM capestable WHERE cape_id = 2)").Scan(&orphans))
+	assert.Equal(t, 0, orphans)
+
+	refs := map[string]int{}
+	rows, err := tx.Query("SELECT blob_key, ref_count FROM texturestable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var key string
+		var count int
+		assert.NoError(t, rows.Scan(&key, &count))
+		refs[key] = count
+	}
+	assert.NoError(t, rows.Err())
+	rows.Close()
+	assert.Equal(t, map[string]int{"shared": 2, "ghost-original": 0, "ghost-previous": 0, "cape": 1}, refs)
+
+	var ownerNameColumns int
+	assert.NoError(t, tx.QueryRow(`SELECT COUNT(*) FROM information_schema.columns
+        WHERE table_schema = 'migration_test' AND column_name = 'owner_name'`).Scan(&ownerNameColumns))
+	assert.Equal(t, 0, ownerNameColumns)
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"SkinRest/pkg/models"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Pages of skins are read with keyset pagination: the cursor holds the sort
+// key of the last skin of a page, and the next page starts right after it.
+// Unlike offsets, pages stay consistent while skins are added or deleted.
+
+const defaultSkinPageLimit int = 20
+
+// pageCursor is the position after which the next page starts
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Name  string `json:"n,omitempty"` // skin name, when sorted by name
+	Id    int    `json:"i"`
+}
+
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Id < 1 {
+		return nil, models.ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s, with backslash as the escape character
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (m *AppContext) GetUserSkinsPage(userData *models.UserData, query *models.SkinQuery) (*models.SkinPage, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultSkinPageLimit
+	}
+	sort, order := query.Sort, query.Order
+	if sort == "" {
+		sort = models.SkinSortCreated
+	}
+	if order == "" {
+		order = models.SortAsc
+	}
+
+	// Filters apply to the total as well as to the page
+	where := []string{"owner_id = $1"}
+	args := []any{userData.Id}
+
+	if query.Type != "" {
+		args = append(args, query.Type)
+		where = append(where, fmt.Sprintf("skin_type = $%d", len(args)))
+	}
+	if query.Name != "" {
+		args = append(args, "%"+escapeLike(query.Name)+"%")
+		where = append(where, fmt.Sprintf(`skin_name ILIKE $%d ESCAPE '\'`, len(args)))
+	}
+
+	page := &models.SkinPage{Skins: []models.SkinData{}}
+
+	err := m.DB.QueryRow("SELECT count(*) FROM skinstable WHERE "+strings.Join(where, " AND "), args...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skin ids grow with creation, and break ties between equal names
+	op, dir := ">", "ASC"
+	if order == models.SortDesc {
+		op, dir = "<", "DESC"
+	}
+	orderBy := "skin_id " + dir
+	if sort == models.SkinSortName {
+		orderBy = "skin_name " + dir + ", skin_id " + dir
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != sort || cursor.Order != order {
+			return nil, models.ErrInvalidCursor
+		}
+
+		if sort == models.SkinSortName {
+			args = append(args, cursor.Name, cursor.Id)
+			where = append(where, fmt.Sprintf("(skin_name, skin_id) %s ($%d, $%d)", op, len(args)-1, len(args)))
+		} else {
+			args = append(args, cursor.Id)
+			where = append(where, fmt.Sprintf("skin_id %s $%d", op, len(args)))
+		}
+	}
+
+	// One extra row tells whether there is a next page
+	args = append(args, limit+1)
+	rows, err := m.DB.Query(fmt.Sprintf("SELECT "+skinColumns+" FROM skinstable WHERE %s ORDER BY %s LIMIT $%d", strings.Join(where, " AND "), orderBy, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		skin, err := m.scanSkin(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Skins = append(page.Skins, *skin)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Skins) > limit {
+		page.Skins = page.Skins[:limit]
+		last := page.Skins[limit-1]
+
+		cursor := pageCursor{Sort: sort, Order: order, Id: last.Id}
+		if sort == models.SkinSortName {
+			cursor.Name = last.Name
+		}
+		page.NextCursor = encodeCursor(cursor)
+	}
+
+	return page, nil
+}
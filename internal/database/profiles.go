@@ -0,0 +1,75 @@
+package database
+
+import (
+	"SkinRest/pkg/models"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+func (m *AppContext) GetProfileByUUID(uuid string) (*models.Profile, error) {
+	return m.getProfile(m.DB.QueryRow("SELECT user_uuid, login FROM userstable WHERE user_uuid = $1", uuid))
+}
+
+func (m *AppContext) GetProfileByName(name string) (*models.Profile, error) {
+	return m.getProfile(m.DB.QueryRow("SELECT user_uuid, login FROM userstable WHERE login = $1", name))
+}
+
+// GetProfilesByNames looks up several profiles at once, unknown names are skipped.
+// Profiles are returned without their skins.
+func (m *AppContext) GetProfilesByNames(names []string) ([]models.Profile, error) {
+	var profiles []models.Profile
+
+	rows, err := m.DB.Query("SELECT user_uuid, login FROM userstable WHERE login = ANY($1)", pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var profile models.Profile
+		if err := rows.Scan(&profile.Id, &profile.Name); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+func (m *AppContext) getProfile(row *sql.Row) (*models.Profile, error) {
+	var profile models.Profile
+
+	if err := row.Scan(&profile.Id, &profile.Name); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	// Game clients only download textures from our own domain,
+	// so the active skin needs a stored texture to be shown
+	skin, err := m.GetActiveSkin(profile.Name)
+	switch {
+	case err == nil:
+		if skin.Texture != "" {
+			profile.Skin = skin
+		}
+	case err != models.ErrActiveSkinNotSet:
+		return nil, err
+	}
+
+	cape, err := m.GetActiveCape(profile.Name)
+	switch {
+	case err == nil:
+		profile.Cape = cape
+	case err != models.ErrActiveCapeNotSet:
+		return nil, err
+	}
+
+	return &profile, nil
+}
@@ -0,0 +1,79 @@
+package database
+
+import "SkinRest/pkg/models"
+
+// SetUserRole gives a user a role. The last admin cannot be demoted, so
+// there is always someone left to manage roles.
+func (m *AppContext) SetUserRole(login string, role string) (*models.UserData, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the admins, two admins demoting each other must not both succeed
+	var admins int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM (SELECT 1 FROM userstable WHERE role = $1 FOR UPDATE) AS admins", models.RoleAdmin).Scan(&admins); err != nil {
+		return nil, err
+	}
+
+	userData, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM userstable WHERE login = $1 FOR UPDATE", login))
+	if err != nil {
+		return nil, err
+	}
+
+	if userData.Role == models.RoleAdmin && role != models.RoleAdmin && admins <= 1 {
+		return nil, models.ErrLastAdmin
+	}
+
+	if _, err := tx.Exec("UPDATE userstable SET role = $1, updated_at = now() WHERE user_id = $2", role, userData.Id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	userData.Role = role
+	return userData, nil
+}
+
+// BootstrapAdmin makes the user the first admin, or fails with
+// ErrAdminExists once there is one
+func (m *AppContext) BootstrapAdmin(userData *models.UserData) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// There is no admin row to lock yet, serialize bootstraps instead
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('bootstrap_admin'))"); err != nil {
+		return err
+	}
+
+	var adminExists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM userstable WHERE role = $1)", models.RoleAdmin).Scan(&adminExists); err != nil {
+		return err
+	}
+
+	if adminExists {
+		return models.ErrAdminExists
+	}
+
+	res, err := tx.Exec("UPDATE userstable SET role = $1, updated_at = now() WHERE user_id = $2", models.RoleAdmin, userData.Id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,313 @@
+package database
+
+import (
+	"SkinRest/config"
+	"SkinRest/pkg/models"
+	"database/sql"
+	"time"
+)
+
+// A session starts at login and lives as long as its refresh tokens are
+// renewed. Every refresh token can be used once: using it marks it used and
+// issues the next one. A used token presented again means it was copied, so
+// the whole session, the family of tokens it belongs to, is revoked.
+
+// CreateSession opens a session for the user on a device and issues its first tokens
+func (m *AppContext) CreateSession(userData *models.UserData, client *models.SessionClient) (*models.TokenPair, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var sessionId int
+	if err := tx.QueryRow(`INSERT INTO sessionstable (user_id, device_label, user_agent, ip)
+        VALUES ($1, $2, $3, $4) RETURNING session_id`, userData.Id, client.Device, client.UserAgent, client.IP).Scan(&sessionId); err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := issueRefreshToken(tx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return newTokenPair(userData, sessionId, refreshToken)
+}
+
+// RefreshSession exchanges a refresh token for a new access token and the next refresh token
+func (m *AppContext) RefreshSession(refreshToken string, client *models.SessionClient) (*models.TokenPair, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var sessionId int
+	var expiresAt time.Time
+	var usedAt, revokedAt *time.Time
+
+	err = tx.QueryRow(`SELECT refreshtokenstable.session_id, refreshtokenstable.expires_at, refreshtokenstable.used_at, sessionstable.revoked_at
+        FROM refreshtokenstable JOIN sessionstable ON sessionstable.session_id = refreshtokenstable.session_id
+        WHERE refreshtokenstable.token_hash = $1 FOR UPDATE`, hashSecret(refreshToken)).Scan(&sessionId, &expiresAt, &usedAt, &revokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	decision, refreshErr := decideRefresh(time.Now(), expiresAt, usedAt, revokedAt)
+	switch decision {
+	case refreshDeny:
+		return nil, refreshErr
+	case refreshRevoke:
+		if _, err := tx.Exec("UPDATE sessionstable SET revoked_at = now() WHERE session_id = $1", sessionId); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, refreshErr
+	}
+
+	if _, err := tx.Exec("UPDATE refreshtokenstable SET used_at = now() WHERE token_hash = $1", hashSecret(refreshToken)); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("UPDATE sessionstable SET last_seen_at = now(), user_agent = $1, ip = $2 WHERE session_id = $3", client.UserAgent, client.IP, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Used tokens are only kept for reuse detection, until they would have expired
+	if _, err := tx.Exec("DELETE FROM refreshtokenstable WHERE session_id = $1 AND expires_at < now()", sessionId); err != nil {
+		return nil, err
+	}
+
+	nextToken, err := issueRefreshToken(tx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	userData, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM userstable WHERE user_id = (SELECT user_id FROM sessionstable WHERE session_id = $1)", sessionId))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return newTokenPair(userData, sessionId, nextToken)
+}
+
+// refreshDecision is what presenting a refresh token leads to
+type refreshDecision int
+
+const (
+	refreshRotate refreshDecision = iota // issue the next token
+	refreshDeny                          // refuse the token, the session is left as it is
+	refreshRevoke                        // refuse the token and revoke its session
+)
+
+// decideRefresh judges a refresh token by its state and that of its session,
+// returning the error to report unless the token can be rotated
+func decideRefresh(now, expiresAt time.Time, usedAt, revokedAt *time.Time) (refreshDecision, error) {
+	if revokedAt != nil {
+		return refreshDeny, models.ErrInvalidRefreshToken
+	}
+
+	if usedAt != nil {
+		// Replay of a rotated token: whoever holds the newer one is not trusted either
+		return refreshRevoke, models.ErrRefreshTokenReused
+	}
+
+	if expiresAt.Before(now) {
+		return refreshDeny, models.ErrRefreshTokenExpired
+	}
+
+	return refreshRotate, nil
+}
+
+// GetSessionUser returns the user of a session that has not been revoked,
+// and notes that the session is in use
+func (m *AppContext) GetSessionUser(sessionId int, userId int) (*models.UserData, error) {
+	userData, err := scanUser(m.DB.QueryRow(`SELECT `+userColumns+` FROM userstable WHERE user_id = (
+            SELECT user_id FROM sessionstable WHERE session_id = $1 AND user_id = $2 AND revoked_at IS NULL
+        )`, sessionId, userId))
+	if err != nil {
+		if err == models.ErrUserNotFound {
+			return nil, models.ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	// last seen is kept to the minute, not every request needs a write
+	_, err = m.DB.Exec("UPDATE sessionstable SET last_seen_at = now() WHERE session_id = $1 AND last_seen_at < now() - interval '1 minute'", sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return userData, nil
+}
+
+// GetUserSessions lists the sessions of the user that can still be refreshed,
+// most recently used first, marking the session currentId as Current
+func (m *AppContext) GetUserSessions(userData *models.UserData, currentId int) ([]models.Session, error) {
+	var sessions []models.Session
+
+	rows, err := m.DB.Query(`SELECT session_id, device_label, user_agent, ip, created_at, last_seen_at FROM sessionstable
+        WHERE user_id = $1 AND revoked_at IS NULL AND EXISTS (
+            SELECT 1 FROM refreshtokenstable WHERE session_id = sessionstable.session_id AND used_at IS NULL AND expires_at > now()
+        )
+        ORDER BY last_seen_at DESC`, userData.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.Id, &session.Device, &session.UserAgent, &session.IP, &session.CreatedAt, &session.LastSeenAt); err != nil {
+			return nil, err
+		}
+		session.Current = session.Id == currentId
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RevokeUserSession ends one of the user's sessions, its refresh token stops
+// working. Its access tokens are refused along with the session, and the
+// token of claims is denied too when the user ends the session it belongs to.
+func (m *AppContext) RevokeUserSession(userData *models.UserData, sessionId int, claims *AccessClaims) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE sessionstable SET revoked_at = now() WHERE session_id = $1 AND user_id = $2 AND revoked_at IS NULL", sessionId, userData.Id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrSessionNotFound
+	}
+
+	if claims.SessionId == sessionId {
+		if err := revokeAccessToken(tx, claims); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// issueRefreshToken stores a new refresh token for the session and returns it
+func issueRefreshToken(tx *sql.Tx, sessionId int) (string, error) {
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := time.Now().Add(config.GetConfig().Auth.RefreshTokenTTL)
+	if _, err := tx.Exec("INSERT INTO refreshtokenstable (token_hash, session_id, expires_at) VALUES ($1, $2, $3)", hash, sessionId, expiresAt); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func newTokenPair(userData *models.UserData, sessionId int, refreshToken string) (*models.TokenPair, error) {
+	accessToken, err := GenerateAccessToken(userData, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    accessTokenLifetime(),
+	}, nil
+}
+
+// EndSession logs the user out of the session of the access token: the
+// session is revoked and the token itself denied until it expires
+func (m *AppContext) EndSession(userData *models.UserData, claims *AccessClaims) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE sessionstable SET revoked_at = now() WHERE session_id = $1 AND user_id = $2 AND revoked_at IS NULL", claims.SessionId, userData.Id)
+	if err != nil {
+		return err
+	}
+
+	if err := revokeAccessToken(tx, claims); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// EndAllSessions logs the user out everywhere. Access tokens of the other
+// sessions are refused along with their sessions.
+func (m *AppContext) EndAllSessions(userData *models.UserData, claims *AccessClaims) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE sessionstable SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL", userData.Id)
+	if err != nil {
+		return err
+	}
+
+	if err := revokeAccessToken(tx, claims); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IsTokenRevoked reports whether the access token with this jti was revoked
+func (m *AppContext) IsTokenRevoked(jti string) (bool, error) {
+	var revoked bool
+	err := m.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM revokedtokenstable WHERE jti = $1 AND expires_at > now())", jti).Scan(&revoked)
+	return revoked, err
+}
+
+// PruneRevokedTokens forgets revoked access tokens that have expired since,
+// their signature check already refuses them, and returns how many it removed
+func (m *AppContext) PruneRevokedTokens() (int64, error) {
+	res, err := m.DB.Exec("DELETE FROM revokedtokenstable WHERE expires_at <= now()")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// revokeAccessToken denies an access token until it expires
+func revokeAccessToken(tx *sql.Tx, claims *AccessClaims) error {
+	_, err := tx.Exec("INSERT INTO revokedtokenstable (jti, expires_at) VALUES ($1, $2) ON CONFLICT DO NOTHING", claims.ID, claims.ExpiresAt.Time)
+	return err
+}
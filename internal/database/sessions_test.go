@@ -0,0 +1,123 @@
+package database
+
+import (
+	"SkinRest/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecideRefresh(t *testing.T) {
+	now := time.Date(2024, 11, 3, 12, 0, 0, 0, time.UTC)
+	valid := now.Add(time.Hour)
+	earlier := now.Add(-time.Minute)
+
+	decision, err := decideRefresh(now, valid, nil, nil)
+	assert.Equal(t, refreshRotate, decision)
+	assert.NoError(t, err)
+
+	// a rotated token presented again revokes the session it belongs to
+	decision, err = decideRefresh(now, valid, &earlier, nil)
+	assert.Equal(t, refreshRevoke, decision)
+	assert.Equal(t, models.ErrRefreshTokenReused, err)
+
+	// even once it has expired, a replay is still a replay
+	decision, err = decideRefresh(now, earlier, &earlier, nil)
+	assert.Equal(t, refreshRevoke, decision)
+	assert.Equal(t, models.ErrRefreshTokenReused, err)
+
+	decision, err = decideRefresh(now, earlier, nil, nil)
+	assert.Equal(t, refreshDeny, decision)
+	assert.Equal(t, models.ErrRefreshTokenExpired, err)
+
+	// tokens of a revoked session are refused without revoking anything again
+	decision, err = decideRefresh(now, valid, &earlier, &earlier)
+	assert.Equal(t, refreshDeny, decision)
+	assert.Equal(t, models.ErrInvalidRefreshToken, err)
+}
+
+// testSession logs the user in on a device and returns the tokens and claims of the session
+func testSession(t *testing.T, m *AppContext, userData *models.UserData, device string) (*models.TokenPair, *AccessClaims) {
+	t.Helper()
+
+	pair, err := m.CreateSession(userData, &models.SessionClient{Device: device})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ParseAccessToken(pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair, claims
+}
+
+func TestRevokeOtherUsersSession(t *testing.T) {
+	m := testContext(t)
+	alice := testUser(t, m)
+	mallory := testUser(t, m)
+
+	_, aliceClaims := testSession(t, m, alice, "Laptop")
+	_, malloryClaims := testSession(t, m, mallory, "Phone")
+
+	err := m.RevokeUserSession(mallory, aliceClaims.SessionId, malloryClaims)
+	assert.Equal(t, models.ErrSessionNotFound, err)
+
+	// the session of alice is untouched
+	_, err = m.GetSessionUser(aliceClaims.SessionId, alice.Id)
+	assert.NoError(t, err)
+}
+
+func TestGetUserSessionsMarksCurrent(t *testing.T) {
+	m := testContext(t)
+	user := testUser(t, m)
+
+	_, laptop := testSession(t, m, user, "Laptop")
+	_, phone := testSession(t, m, user, "Phone")
+
+	sessions, err := m.GetUserSessions(user, phone.SessionId)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	for _, session := range sessions {
+		assert.Equal(t, session.Id == phone.SessionId, session.Current, session.Device)
+		assert.Contains(t, []int{laptop.SessionId, phone.SessionId}, session.Id)
+	}
+}
+
+func TestRevokeUserSession(t *testing.T) {
+	m := testContext(t)
+	user := testUser(t, m)
+
+	laptopPair, laptop := testSession(t, m, user, "Laptop")
+	phonePair, phone := testSession(t, m, user, "Phone")
+
+	// the phone logs the laptop out
+	assert.NoError(t, m.RevokeUserSession(user, laptop.SessionId, phone))
+
+	_, err := m.RefreshSession(laptopPair.RefreshToken, &models.SessionClient{})
+	assert.Equal(t, models.ErrInvalidRefreshToken, err)
+
+	// the laptop's access tokens are refused with their session
+	_, err = m.GetSessionUser(laptop.SessionId, user.Id)
+	assert.Equal(t, models.ErrSessionNotFound, err)
+
+	revoked, err := m.IsTokenRevoked(phone.ID)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// ending its own session denies the phone's access token outright
+	assert.NoError(t, m.RevokeUserSession(user, phone.SessionId, phone))
+
+	revoked, err = m.IsTokenRevoked(phone.ID)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	_, err = m.RefreshSession(phonePair.RefreshToken, &models.SessionClient{})
+	assert.Equal(t, models.ErrInvalidRefreshToken, err)
+
+	sessions, err := m.GetUserSessions(user, phone.SessionId)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
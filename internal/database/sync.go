@@ -0,0 +1,194 @@
+package database
+
+import (
+	"SkinRest/internal/texture"
+	"SkinRest/pkg/models"
+	"database/sql"
+)
+
+// GetNicknameSkins returns every skin whose source is a Mojang nickname rather than a URL
+func (m *AppContext) GetNicknameSkins() ([]models.SkinData, error) {
+	var skins []models.SkinData
+
+	rows, err := m.DB.Query("SELECT " + skinColumns + ` FROM skinstable WHERE skin_src <> '' AND skin_src !~* '^https?://' ORDER BY skin_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		skin, err := m.scanSkin(rows)
+		if err != nil {
+			return nil, err
+		}
+		skins = append(skins, *skin)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return skins, nil
+}
+
+// ResyncSkin stores the current texture of a nickname-sourced skin, with the
+// model Mojang reports for it as its type, and records the change in its
+// history. The skin is only updated while its texture is still previousKey,
+// so a change made in the meantime is never overwritten, and only if the
+// texture really changed, so the type the user declared for the texture
+// they chose stays; it reports whether the skin was updated.
+func (m *AppContext) ResyncSkin(id int, previousKey string, skinTexture *models.SkinTexture, skinType string) (bool, error) {
+	var blobKey, originalBlobKey, currentType string
+
+	tx, err := m.beginTextureTx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("SELECT blob_key, original_blob_key, skin_type FROM skinstable WHERE skin_id = $1 FOR UPDATE", id).Scan(&blobKey, &originalBlobKey, &currentType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil // deleted in the meantime
+		}
+		return false, err
+	}
+
+	if blobKey != previousKey {
+		return false, nil
+	}
+
+	unchanged, err := m.storedTextureIs(blobKey, skinTexture.Data)
+	if err != nil {
+		return false, err
+	}
+	if unchanged {
+		return false, nil
+	}
+
+	// A skin without a stored texture was served from its source, the
+	// texture stored now, so it keeps the type it was declared with
+	if blobKey == "" {
+		skinType = currentType
+	}
+
+	newBlobKey, newOriginalBlobKey, err := m.acquireSkinTexture(tx, skinTexture)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec("UPDATE skinstable SET blob_key = $1, original_blob_key = $2, source_url = $3, skin_type = $4, version = version + 1, updated_at = now() WHERE skin_id = $5", newBlobKey, newOriginalBlobKey, skinTexture.SourceURL, skinType, id); err != nil {
+		return false, err
+	}
+
+	if err := m.snapshotVersion(tx.Tx, id); err != nil {
+		return false, err
+	}
+
+	// The history entry takes over the skin's reference on the previous
+	// texture and takes one of its own on the new one
+	if _, err := m.acquireTexture(tx, skinTexture.Data); err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec("INSERT INTO skinhistorytable (skin_id, previous_blob_key, blob_key, skin_type, source_url) VALUES ($1, $2, $3, $4, $5)", id, blobKey, newBlobKey, skinType, skinTexture.SourceURL)
+	if err != nil {
+		return false, err
+	}
+
+	if err := m.releaseTexture(tx, originalBlobKey); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// storedTextureIs reports whether the blob of key holds the texture data.
+// Content-addressed keys are compared as they are, blobs stored under a
+// legacy random key are hashed to find out.
+func (m *AppContext) storedTextureIs(key string, data []byte) (bool, error) {
+	hash, err := texture.HashPNG(data)
+	if err != nil {
+		return false, err
+	}
+
+	if key == hash || len(key) != legacyBlobKeyLength {
+		return key == hash, nil
+	}
+
+	stored, err := m.Storage.Get(key)
+	if err != nil {
+		if err == models.ErrBlobNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	storedHash, err := texture.HashPNG(stored)
+	if err != nil {
+		return false, err
+	}
+	return storedHash == hash, nil
+}
+
+// GetSkinHistory lists the texture changes of one of the user's skins, newest first
+func (m *AppContext) GetSkinHistory(userData *models.UserData, id int) ([]models.SkinHistoryEntry, error) {
+	if _, err := m.GetUserSkin(userData, id); err != nil {
+		return nil, err
+	}
+
+	history := []models.SkinHistoryEntry{}
+
+	rows, err := m.DB.Query("SELECT history_id, previous_blob_key, blob_key, skin_type, source_url, changed_at FROM skinhistorytable WHERE skin_id = $1 ORDER BY history_id DESC", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.SkinHistoryEntry
+		var previousBlobKey, blobKey string
+
+		if err := rows.Scan(&entry.Id, &previousBlobKey, &blobKey, &entry.Type, &entry.SourceURL, &entry.ChangedAt); err != nil {
+			return nil, err
+		}
+
+		entry.PreviousTexture = m.textureURL(previousBlobKey)
+		entry.Texture = m.textureURL(blobKey)
+		history = append(history, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// deleteSkinHistory removes the history of one of the user's skins and
+// returns the blob keys its entries referenced
+func deleteSkinHistory(tx *sql.Tx, userData *models.UserData, id int) ([]string, error) {
+	var keys []string
+
+	rows, err := tx.Query(`DELETE FROM skinhistorytable WHERE skin_id = $1
+        AND skin_id IN (SELECT skin_id FROM skinstable WHERE owner_id = $2)
+        RETURNING previous_blob_key, blob_key`, id, userData.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var previousBlobKey, blobKey string
+		if err := rows.Scan(&previousBlobKey, &blobKey); err != nil {
+			return nil, err
+		}
+		keys = append(keys, previousBlobKey, blobKey)
+	}
+
+	return keys, rows.Err()
+}
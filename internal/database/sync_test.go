@@ -0,0 +1,56 @@
+package database
+
+import (
+	"SkinRest/pkg/models"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResyncSkinType(t *testing.T) {
+	m := testContext(t)
+	user := testUser(t, m)
+
+	// added by nickname as a slim skin, served from Mojang until the first sync
+	skin, err := m.AddNewSkin(user, &models.Skin{Name: "Synced", Type: models.SkinTypeSlim, Src: "Alex"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := testTexture(t)
+	updated, err := m.ResyncSkin(skin.Id, "", first, models.SkinTypeClassic)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	skin, err = m.GetUserSkin(user, skin.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SkinTypeSlim, skin.Type, "the texture was already shown, the declared type stays")
+	assert.NotEmpty(t, skin.Hash)
+
+	// the player changed skin, the model Mojang reports comes with it
+	second := testTexture(t)
+	updated, err = m.ResyncSkin(skin.Id, skin.Hash, second, models.SkinTypeClassic)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	skin, err = m.GetUserSkin(user, skin.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SkinTypeClassic, skin.Type)
+
+	// a texture stored under a legacy random key is the same texture
+	legacyKey := fmt.Sprintf("%032x", time.Now().UnixNano())
+	assert.NoError(t, m.Storage.Put(legacyKey, second.Data))
+	_, err = m.DB.Exec("UPDATE skinstable SET blob_key = $1 WHERE skin_id = $2", legacyKey, skin.Id)
+	assert.NoError(t, err)
+
+	updated, err = m.ResyncSkin(skin.Id, legacyKey, second, models.SkinTypeSlim)
+	assert.NoError(t, err)
+	assert.False(t, updated)
+
+	skin, err = m.GetUserSkin(user, skin.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SkinTypeClassic, skin.Type)
+	assert.Equal(t, legacyKey, skin.Hash)
+}
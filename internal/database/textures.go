@@ -0,0 +1,287 @@
+package database
+
+import (
+	"SkinRest/internal/texture"
+	"SkinRest/pkg/models"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+)
+
+// Textures are content-addressed: the blob key of a texture is its content
+// hash, and texturestable counts how many rows reference each blob so that
+// identical uploads share one stored copy. Keys issued before deduplication
+// are random 32-character hex strings and are counted the same way.
+const (
+	blobKeyLength       int = 64 // SHA-256 hex
+	legacyBlobKeyLength int = 32 // random keys from before content addressing
+)
+
+// textureTx is a transaction that takes or drops references on stored
+// textures. Storage is not transactional, so blobs are only deleted once the
+// transaction that dropped their last reference has committed, and blobs it
+// wrote are removed again if it rolls back.
+type textureTx struct {
+	*sql.Tx
+	m        *AppContext
+	stored   []string // blobs written by this transaction
+	released []string // blobs whose last reference this transaction dropped
+	done     bool
+}
+
+func (m *AppContext) beginTextureTx() (*textureTx, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &textureTx{Tx: tx, m: m}, nil
+}
+
+// Commit commits the transaction, then deletes the blobs it left unreferenced
+func (tx *textureTx) Commit() error {
+	tx.done = true
+	if err := tx.Tx.Commit(); err != nil {
+		tx.m.sweepTextures(tx.stored)
+		return err
+	}
+
+	tx.m.sweepTextures(tx.released)
+	return nil
+}
+
+// Rollback aborts the transaction, then deletes the blobs it wrote
+func (tx *textureTx) Rollback() error {
+	err := tx.Tx.Rollback()
+	if !tx.done {
+		tx.done = true
+		tx.m.sweepTextures(tx.stored)
+	}
+	return err
+}
+
+// acquireTexture stores the texture under its content hash, reusing the blob
+// if an identical texture is already stored, and takes a reference on it.
+// The blob is written while the texturestable row is locked by tx, so a
+// concurrent sweep of the same key cannot remove it underneath us.
+func (m *AppContext) acquireTexture(tx *textureTx, data []byte) (string, error) {
+	key, err := texture.HashPNG(data)
+	if err != nil {
+		return "", err
+	}
+
+	var refCount int
+	err = tx.QueryRow(`INSERT INTO texturestable (blob_key, ref_count) VALUES ($1, 1)
+		ON CONFLICT (blob_key) DO UPDATE SET ref_count = texturestable.ref_count + 1
+		RETURNING ref_count`, key).Scan(&refCount)
+	if err != nil {
+		return "", err
+	}
+
+	if refCount == 1 { // first reference, nothing stored yet
+		tx.stored = append(tx.stored, key)
+		if err := m.Storage.Put(key, data); err != nil {
+			return "", err
+		}
+	}
+
+	return key, nil
+}
+
+// retainTexture takes one more reference on a blob that is already stored
+func retainTexture(tx *sql.Tx, key string) error {
+	if key == "" {
+		return nil
+	}
+
+	_, err := tx.Exec("UPDATE texturestable SET ref_count = ref_count + 1 WHERE blob_key = $1", key)
+	return err
+}
+
+// releaseTexture drops one reference to a blob. The blob is deleted once tx
+// commits if that was the last reference.
+func (m *AppContext) releaseTexture(tx *textureTx, key string) error {
+	if key == "" {
+		return nil
+	}
+
+	var refCount int
+	err := tx.QueryRow("UPDATE texturestable SET ref_count = ref_count - 1 WHERE blob_key = $1 RETURNING ref_count", key).Scan(&refCount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil // not tracked, leave the blob alone
+		}
+		return err
+	}
+
+	if refCount == 0 {
+		tx.released = append(tx.released, key)
+	}
+
+	return nil
+}
+
+// sweepTextures deletes the blobs among keys that nothing references. Errors
+// are only logged, a blob that fails to be deleted keeps its row with no
+// references and is written again by the next acquireTexture.
+func (m *AppContext) sweepTextures(keys []string) {
+	for _, key := range keys {
+		if err := m.sweepTexture(key); err != nil {
+			m.Logger.Error("texture sweep: " + key + ": " + err.Error())
+		}
+	}
+}
+
+// sweepTexture deletes a blob if it has no references. The texturestable row
+// is created if missing and locked while the blob is deleted, so a concurrent
+// acquireTexture waits for the sweep and then stores the blob again.
+func (m *AppContext) sweepTexture(key string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var refCount int
+	err = tx.QueryRow(`INSERT INTO texturestable (blob_key, ref_count) VALUES ($1, 0)
+		ON CONFLICT (blob_key) DO UPDATE SET ref_count = texturestable.ref_count
+		RETURNING ref_count`, key).Scan(&refCount)
+	if err != nil {
+		return err
+	}
+
+	if refCount > 0 {
+		return nil // referenced again in the meantime
+	}
+
+	if _, err := tx.Exec("DELETE FROM texturestable WHERE blob_key = $1", key); err != nil {
+		return err
+	}
+
+	m.Renders.Invalidate(key)
+
+	if err := m.Storage.Delete(key); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// acquireSkinTexture takes references on the canonical and, if present, original texture of a skin
+func (m *AppContext) acquireSkinTexture(tx *textureTx, texture *models.SkinTexture) (string, string, error) {
+	blobKey, err := m.acquireTexture(tx, texture.Data)
+	if err != nil {
+		return "", "", err
+	}
+
+	var originalBlobKey string
+	if texture.Original != nil {
+		originalBlobKey, err = m.acquireTexture(tx, texture.Original)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return blobKey, originalBlobKey, nil
+}
+
+// GetSkinTexture returns the stored canonical texture of one of the user's skins
+func (m *AppContext) GetSkinTexture(userData *models.UserData, id int) ([]byte, error) {
+	var blobKey string
+
+	err := m.DB.QueryRow("SELECT blob_key FROM skinstable WHERE skin_id = $1 AND owner_id = $2", id, userData.Id).Scan(&blobKey)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrSkinNotFound
+		}
+		return nil, err
+	}
+
+	if blobKey == "" {
+		return nil, models.ErrTextureNotFound
+	}
+
+	return m.GetTexture(blobKey)
+}
+
+// SetSkinTexture replaces the stored texture of one of the user's skins
+func (m *AppContext) SetSkinTexture(userData *models.UserData, id int, texture *models.SkinTexture) (*models.SkinData, error) {
+	var oldBlobKey, oldOriginalBlobKey string
+
+	tx, err := m.beginTextureTx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("SELECT blob_key, original_blob_key FROM skinstable WHERE skin_id = $1 AND owner_id = $2 FOR UPDATE", id, userData.Id).Scan(&oldBlobKey, &oldOriginalBlobKey)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrSkinNotFound
+		}
+		return nil, err
+	}
+
+	// take the new references before dropping the old ones, they may share blobs
+	blobKey, originalBlobKey, err := m.acquireSkinTexture(tx, texture)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("UPDATE skinstable SET blob_key = $1, original_blob_key = $2, version = version + 1, updated_at = now() WHERE skin_id = $3", blobKey, originalBlobKey, id); err != nil {
+		return nil, err
+	}
+
+	if err := m.snapshotVersion(tx.Tx, id); err != nil {
+		return nil, err
+	}
+
+	if err := m.releaseTexture(tx, oldBlobKey); err != nil {
+		return nil, err
+	}
+	if err := m.releaseTexture(tx, oldOriginalBlobKey); err != nil {
+		return nil, err
+	}
+
+	skinData, err := m.scanSkin(tx.QueryRow("SELECT "+skinColumns+" FROM skinstable WHERE skin_id = $1", id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return skinData, nil
+}
+
+func (m *AppContext) textureURL(key string) string {
+	if key == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/api/v1/textures/%s", m.BaseURL, key)
+}
+
+func (m *AppContext) GetTexture(key string) ([]byte, error) {
+	if !validBlobKey(key) {
+		return nil, models.ErrTextureNotFound
+	}
+
+	data, err := m.Storage.Get(key)
+	if err != nil {
+		if err == models.ErrBlobNotFound {
+			return nil, models.ErrTextureNotFound
+		}
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// validBlobKey rejects anything that is not a key produced by this package
+func validBlobKey(key string) bool {
+	if len(key) != blobKeyLength && len(key) != legacyBlobKeyLength {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
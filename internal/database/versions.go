@@ -0,0 +1,179 @@
+package database
+
+import (
+	"SkinRest/pkg/models"
+	"database/sql"
+)
+
+// Every state a skin has been in is kept in skinversionstable. The skinstable
+// row is the current state and its version column the number of the latest
+// version; changing a skin bumps the number and snapshots the new state.
+// Versions are never modified, and hold references on their textures so
+// that any of them can be restored.
+
+// snapshotVersion records the current state of a skin as a version
+func (m *AppContext) snapshotVersion(tx *sql.Tx, id int) error {
+	var blobKey, originalBlobKey string
+
+	err := tx.QueryRow(`INSERT INTO skinversionstable (skin_id, version, skin_name, skin_type, skin_src, blob_key, original_blob_key, source_url)
+        SELECT skin_id, version, skin_name, skin_type, skin_src, blob_key, original_blob_key, source_url FROM skinstable WHERE skin_id = $1
+        RETURNING blob_key, original_blob_key`, id).Scan(&blobKey, &originalBlobKey)
+	if err != nil {
+		return err
+	}
+
+	if err := retainTexture(tx, blobKey); err != nil {
+		return err
+	}
+	return retainTexture(tx, originalBlobKey)
+}
+
+// GetSkinVersions lists the versions of one of the user's skins, newest first
+func (m *AppContext) GetSkinVersions(userData *models.UserData, id int) ([]models.SkinVersion, error) {
+	if _, err := m.GetUserSkin(userData, id); err != nil {
+		return nil, err
+	}
+
+	versions := []models.SkinVersion{}
+
+	rows, err := m.DB.Query("SELECT "+versionColumns+" FROM skinversionstable WHERE skin_id = $1 ORDER BY version DESC", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		version, err := m.scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, *version)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetSkinVersion returns one version of one of the user's skins
+func (m *AppContext) GetSkinVersion(userData *models.UserData, id int, version int) (*models.SkinVersion, error) {
+	if _, err := m.GetUserSkin(userData, id); err != nil {
+		return nil, err
+	}
+
+	skinVersion, err := m.scanVersion(m.DB.QueryRow("SELECT "+versionColumns+" FROM skinversionstable WHERE skin_id = $1 AND version = $2", id, version))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrSkinVersionNotFound
+		}
+		return nil, err
+	}
+
+	return skinVersion, nil
+}
+
+// RestoreSkinVersion rolls one of the user's skins back to an earlier
+// version. The restored state becomes a new version, so history is never lost.
+func (m *AppContext) RestoreSkinVersion(userData *models.UserData, id int, version int) (*models.SkinData, error) {
+	var oldBlobKey, oldOriginalBlobKey string
+
+	tx, err := m.beginTextureTx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("SELECT blob_key, original_blob_key FROM skinstable WHERE skin_id = $1 AND owner_id = $2 FOR UPDATE", id, userData.Id).Scan(&oldBlobKey, &oldOriginalBlobKey)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrSkinNotFound
+		}
+		return nil, err
+	}
+
+	restored, err := m.scanVersion(tx.QueryRow("SELECT "+versionColumns+" FROM skinversionstable WHERE skin_id = $1 AND version = $2", id, version))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrSkinVersionNotFound
+		}
+		return nil, err
+	}
+
+	// the version keeps its own references, the skin takes new ones
+	if err := retainTexture(tx.Tx, restored.Hash); err != nil {
+		return nil, err
+	}
+	if err := retainTexture(tx.Tx, restored.OriginalHash); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`UPDATE skinstable SET skin_name = $1, skin_type = $2, skin_src = $3, blob_key = $4, original_blob_key = $5, source_url = $6, version = version + 1, updated_at = now()
+        WHERE skin_id = $7`, restored.Name, restored.Type, restored.Src, restored.Hash, restored.OriginalHash, restored.SourceURL, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.releaseTexture(tx, oldBlobKey); err != nil {
+		return nil, err
+	}
+	if err := m.releaseTexture(tx, oldOriginalBlobKey); err != nil {
+		return nil, err
+	}
+
+	if err := m.snapshotVersion(tx.Tx, id); err != nil {
+		return nil, err
+	}
+
+	skinData, err := m.scanSkin(tx.QueryRow("SELECT "+skinColumns+" FROM skinstable WHERE skin_id = $1", id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return skinData, nil
+}
+
+// deleteSkinVersions removes the versions of one of the user's skins and
+// returns the blob keys they referenced
+func deleteSkinVersions(tx *sql.Tx, userData *models.UserData, id int) ([]string, error) {
+	var keys []string
+
+	rows, err := tx.Query(`DELETE FROM skinversionstable WHERE skin_id = $1
+        AND skin_id IN (SELECT skin_id FROM skinstable WHERE owner_id = $2)
+        RETURNING blob_key, original_blob_key`, id, userData.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var blobKey, originalBlobKey string
+		if err := rows.Scan(&blobKey, &originalBlobKey); err != nil {
+			return nil, err
+		}
+		keys = append(keys, blobKey, originalBlobKey)
+	}
+
+	return keys, rows.Err()
+}
+
+const versionColumns = "version, skin_name, skin_type, skin_src, blob_key, original_blob_key, source_url, created_at"
+
+// scanVersion reads a skinversionstable row selected with versionColumns
+func (m *AppContext) scanVersion(row rowScanner) (*models.SkinVersion, error) {
+	var version models.SkinVersion
+
+	if err := row.Scan(&version.Version, &version.Name, &version.Type, &version.Src, &version.Hash, &version.OriginalHash, &version.SourceURL, &version.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	version.Texture = m.textureURL(version.Hash)
+	version.OriginalTexture = m.textureURL(version.OriginalHash)
+
+	return &version, nil
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"SkinRest/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkinVersionTextureReferences(t *testing.T) {
+	m := testContext(t)
+	user := testUser(t, m)
+
+	// expect checks the references held on each texture and that exactly the referenced ones are stored
+	expect := func(step string, counts map[string]int) {
+		t.Helper()
+		for key, count := range counts {
+			assert.Equal(t, count, refCount(t, m, key), step)
+			assert.Equal(t, count > 0, blobStored(t, m, key), step)
+		}
+	}
+
+	skin, err := m.AddNewSkin(user, &models.Skin{Name: "Versions", Type: models.SkinTypeClassic}, testTexture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := skin.Hash
+
+	// every texture is referenced by the skin and by the version that snapshot it
+	expect("add", map[string]int{first: 2})
+
+	skin, err = m.UpdateUserSkin(user, skin.Id, &models.Skin{Name: "Versions", Type: models.SkinTypeClassic}, testTexture(t), skin.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := skin.Hash
+	expect("first update", map[string]int{first: 1, second: 2})
+
+	skin, err = m.UpdateUserSkin(user, skin.Id, &models.Skin{Name: "Versions", Type: models.SkinTypeClassic}, testTexture(t), skin.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	third := skin.Hash
+	expect("second update", map[string]int{first: 1, second: 1, third: 2})
+
+	// the restored state is a new version, referencing the first texture again
+	skin, err = m.RestoreSkinVersion(user, skin.Id, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 4, skin.Version)
+	assert.Equal(t, first, skin.Hash)
+	expect("restore", map[string]int{first: 3, second: 1, third: 1})
+
+	versions, err := m.GetSkinVersions(user, skin.Id)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 4)
+
+	// deleting the skin drops its versions, and with them the last references
+	assert.NoError(t, m.DeleteUserSkin(user, skin.Id))
+	expect("delete", map[string]int{first: 0, second: 0, third: 0})
+}
@@ -1,20 +1,19 @@
 package middleware
 
 import (
-	"SkinRest/config"
 	"SkinRest/internal/database"
 	"SkinRest/pkg/models"
 
 	"net/http"
+	"slices"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 const (
 	authorizationHeader = "Authorization"
+	apiKeyHeader        = "X-Api-Key"
 )
 
 func ApiKeyAuth() gin.HandlerFunc {
@@ -29,6 +28,12 @@ func ApiKeyAuth() gin.HandlerFunc {
 
 		authHeader := c.GetHeader(authorizationHeader)
 
+		// Personal API keys may come in their own header or as a Bearer token
+		if authHeader == "" && c.GetHeader(apiKeyHeader) != "" {
+			apiKeyAuth(c, appctx, c.GetHeader(apiKeyHeader))
+			return
+		}
+
 		if authHeader == "" {
 
 			c.JSON(http.StatusUnauthorized, gin.H{"error": models.ErrTokenNotProvided.Error()})
@@ -44,13 +49,41 @@ func ApiKeyAuth() gin.HandlerFunc {
 
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 
-		userData, err := appctx.GetUserFromToken(token)
+		if database.IsApiKey(token) {
+			apiKeyAuth(c, appctx, token)
+			return
+		}
+
+		claims, err := database.ParseAccessToken(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		// Tokens are refused after a logout, until they would have expired anyway
+		revoked, err := appctx.IsTokenRevoked(claims.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			appctx.Logger.Error(err.Error())
+			c.Abort()
+			return
+		}
+
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": models.ErrTokenRevoked.Error()})
+			c.Abort()
+			return
+		}
+
+		userData, err := appctx.GetSessionUser(claims.SessionId, claims.UserId)
 		if err != nil {
-			if err.Error() == models.ErrUserNotFound.Error() {
+			if err.Error() == models.ErrSessionNotFound.Error() {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 				c.Abort()
 				return
 			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 			appctx.Logger.Error(err.Error())
 			c.Abort()
 			return
@@ -58,65 +91,66 @@ func ApiKeyAuth() gin.HandlerFunc {
 		}
 
 		c.Set("userData", userData)
+		c.Set("sessionId", claims.SessionId)
+		c.Set("tokenClaims", claims)
 		c.Next()
 
 	}
 }
 
-func ValidateAuthToken() gin.HandlerFunc {
-	return func(c *gin.Context) {
-
-		authHeader := c.GetHeader(authorizationHeader)
-
-		if authHeader == "" {
-
-			c.JSON(http.StatusUnauthorized, gin.H{"error": models.ErrTokenNotProvided.Error()})
-			c.Abort()
-			return
-		}
-
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": models.ErrInvalidTokenFormat.Error()})
+// apiKeyAuth authenticates a request made with a personal API key, whose
+// scopes are checked by RequireScope
+func apiKeyAuth(c *gin.Context, appctx *database.AppContext, key string) {
+	userData, scopes, err := appctx.GetApiKeyUser(key)
+	if err != nil {
+		if err.Error() == models.ErrInvalidApiKey.Error() {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		appctx.Logger.Error(err.Error())
+		c.Abort()
+		return
+	}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-
-		token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrInvalidSigningMethod.Error()})
-				c.Abort()
-				return nil, nil
-			}
-			return []byte(config.GetConfig().Auth.JwtSecret), nil
-		})
-
-		if err != nil {
+	c.Set("userData", userData)
+	c.Set("apiKeyScopes", scopes)
+	c.Next()
+}
 
-			c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrInvalidToken.Error() + ": " + err.Error()})
-			c.Abort()
+// RequireScope limits API keys to the requests their scopes allow on a
+// resource: the read scope for GET and HEAD, the write scope for the rest.
+// Requests made with an access token are not limited.
+func RequireScope(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, isApiKey := c.Get("apiKeyScopes")
+		if !isApiKey {
+			c.Next()
 			return
 		}
 
-		if !token.Valid {
-
-			c.JSON(http.StatusUnauthorized, gin.H{"error": models.ErrInvalidToken.Error()})
-			c.Abort()
-			return
+		scope := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = read
 		}
 
-		claims := token.Claims
-
-		expTime, err := claims.GetExpirationTime()
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrInvalidTokenClaims.Error() + ": 'exp' claim is missing or not an integer."})
+		if scopes, _ := value.([]string); !slices.Contains(scopes, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": models.ErrInsufficientScope.Error()})
 			c.Abort()
 			return
 		}
 
-		if expTime.Before(time.Now()) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": models.ErrTokenExpired.Error()})
+		c.Next()
+	}
+}
+
+// RequireSession limits a route to requests made with an access token,
+// refusing API keys. It goes after ApiKeyAuth, which verified the token.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("tokenClaims"); !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": models.ErrTokenNotProvided.Error()})
 			c.Abort()
 			return
 		}
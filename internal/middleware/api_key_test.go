@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"SkinRest/internal/database"
+	"SkinRest/pkg/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// testRouter serves GET and POST / through middleware, after seed has set up
+// the context the way the middleware running before it would
+func testRouter(seed gin.HandlerFunc, middleware ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(seed)
+	r.Use(middleware...)
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/", ok)
+	r.POST("/", ok)
+	return r
+}
+
+func status(r *gin.Engine, method string) int {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, "/", nil))
+	return w.Code
+}
+
+func scopedRouter(scopes []string) *gin.Engine {
+	return testRouter(func(c *gin.Context) {
+		if scopes != nil {
+			c.Set("apiKeyScopes", scopes)
+		}
+	}, RequireScope(models.ScopeSkinsRead, models.ScopeSkinsWrite))
+}
+
+func TestRequireScope(t *testing.T) {
+	readOnly := scopedRouter([]string{models.ScopeSkinsRead, models.ScopeCapesWrite})
+	assert.Equal(t, http.StatusOK, status(readOnly, http.MethodGet))
+	assert.Equal(t, http.StatusForbidden, status(readOnly, http.MethodPost))
+
+	writeOnly := scopedRouter([]string{models.ScopeSkinsWrite})
+	assert.Equal(t, http.StatusForbidden, status(writeOnly, http.MethodGet))
+	assert.Equal(t, http.StatusOK, status(writeOnly, http.MethodPost))
+
+	// access tokens are not limited by scopes
+	session := scopedRouter(nil)
+	assert.Equal(t, http.StatusOK, status(session, http.MethodGet))
+	assert.Equal(t, http.StatusOK, status(session, http.MethodPost))
+}
+
+func TestRequireSession(t *testing.T) {
+	session := testRouter(func(c *gin.Context) {
+		c.Set("tokenClaims", &database.AccessClaims{SessionId: 1})
+	}, RequireSession())
+	assert.Equal(t, http.StatusOK, status(session, http.MethodGet))
+
+	apiKey := testRouter(func(c *gin.Context) {
+		c.Set("apiKeyScopes", []string{models.ScopeSkinsRead})
+	}, RequireSession())
+	assert.Equal(t, http.StatusUnauthorized, status(apiKey, http.MethodGet))
+}
+
+func TestApiKeyAuthRefusesRevokedToken(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	t.Setenv("DATABASE_USER", "test")
+	t.Setenv("DATABASE_PASSWORD", "test")
+	t.Setenv("DATABASE_NAME", "test")
+	t.Setenv("AUTH_JWT_SECRET", "test-secret")
+
+	db, err := database.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	appctx := &database.AppContext{DB: db, Logger: zap.NewNop()}
+
+	user := &models.User{Login: fmt.Sprintf("t%d", time.Now().UnixNano()%1e15), Password: "password"}
+	if err := appctx.CreateNewUser(user); err != nil {
+		t.Fatal(err)
+	}
+	userData, err := appctx.GetInfoUser(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Exec("DELETE FROM userstable WHERE user_id = $1", userData.Id)
+
+	pair, err := appctx.CreateSession(userData, &models.SessionClient{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := database.ParseAccessToken(pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := testRouter(func(c *gin.Context) { c.Set("appCtx", appctx) }, ApiKeyAuth(), RequireSession())
+	request := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(authorizationHeader, "Bearer "+pair.AccessToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request().Code)
+
+	// deny the jti alone, the token is still signed and its session still open
+	_, err = db.Exec("INSERT INTO revokedtokenstable (jti, expires_at) VALUES ($1, $2)", claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := request()
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), models.ErrTokenRevoked.Error())
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"SkinRest/pkg/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole lets through users whose role grants at least the powers of
+// role. It goes after ApiKeyAuth; requests made with an API key are refused,
+// operator powers are only available to users who logged in.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isApiKey := c.Get("apiKeyScopes"); isApiKey {
+			c.JSON(http.StatusForbidden, gin.H{"error": models.ErrRoleNeedsSession.Error()})
+			c.Abort()
+			return
+		}
+
+		userData, exists := c.MustGet("userData").(*models.UserData)
+		if !exists {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			c.Abort()
+			return
+		}
+
+		if !models.HasRole(userData.Role, role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": models.ErrInsufficientRole.Error()})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"SkinRest/pkg/models"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func roleStatus(role string, apiKey bool, required string) int {
+	r := testRouter(func(c *gin.Context) {
+		c.Set("userData", &models.UserData{Role: role})
+		if apiKey {
+			c.Set("apiKeyScopes", []string{models.ScopeSkinsRead})
+		}
+	}, RequireRole(required))
+	return status(r, http.MethodGet)
+}
+
+func TestRequireRole(t *testing.T) {
+	assert.Equal(t, http.StatusOK, roleStatus(models.RoleAdmin, false, models.RoleAdmin))
+	assert.Equal(t, http.StatusOK, roleStatus(models.RoleAdmin, false, models.RoleModerator))
+	assert.Equal(t, http.StatusOK, roleStatus(models.RoleModerator, false, models.RoleModerator))
+	assert.Equal(t, http.StatusForbidden, roleStatus(models.RoleModerator, false, models.RoleAdmin))
+	assert.Equal(t, http.StatusForbidden, roleStatus(models.RoleUser, false, models.RoleModerator))
+	assert.Equal(t, http.StatusForbidden, roleStatus("", false, models.RoleUser))
+
+	// operator powers are not handed to API keys
+	assert.Equal(t, http.StatusForbidden, roleStatus(models.RoleAdmin, true, models.RoleAdmin))
+}
@@ -0,0 +1,134 @@
+package mojang
+
+import (
+	"SkinRest/pkg/models"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Minecraft nicknames are 3 to 16 letters, digits and underscores
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,16}$`)
+
+// ValidName reports whether name can be a Minecraft nickname
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Client talks to the Mojang profile and session servers. The base URLs are
+// configurable so that tests and mirrors can stand in for Mojang.
+type Client struct {
+	APIURL     string
+	SessionURL string
+	HTTP       *http.Client
+}
+
+func NewClient(apiURL, sessionURL string, timeout time.Duration) *Client {
+	return &Client{
+		APIURL:     strings.TrimSuffix(apiURL, "/"),
+		SessionURL: strings.TrimSuffix(sessionURL, "/"),
+		HTTP:       &http.Client{Timeout: timeout},
+	}
+}
+
+// Skin is the current skin of a Minecraft profile
+type Skin struct {
+	URL   string
+	Model string // models.SkinTypeClassic or models.SkinTypeSlim
+}
+
+type profile struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Properties []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"properties"`
+}
+
+type texturesProperty struct {
+	Textures struct {
+		Skin struct {
+			URL      string `json:"url"`
+			Metadata struct {
+				Model string `json:"model"`
+			} `json:"metadata"`
+		} `json:"SKIN"`
+	} `json:"textures"`
+}
+
+// Skin resolves a Minecraft nickname to its current skin: the profile id is
+// looked up by name, then the session server's textures property is decoded
+func (c *Client) Skin(name string) (*Skin, error) {
+	var p profile
+	if err := c.getJSON(c.APIURL+"/users/profiles/minecraft/"+url.PathEscape(name), &p); err != nil {
+		return nil, err
+	}
+
+	if err := c.getJSON(c.SessionURL+"/session/minecraft/profile/"+url.PathEscape(p.Id), &p); err != nil {
+		return nil, err
+	}
+
+	for _, prop := range p.Properties {
+		if prop.Name != "textures" {
+			continue
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(prop.Value)
+		if err != nil {
+			return nil, models.ErrSkinSourceUnreachable
+		}
+
+		var textures texturesProperty
+		if err := json.Unmarshal(raw, &textures); err != nil {
+			return nil, models.ErrSkinSourceUnreachable
+		}
+
+		if textures.Textures.Skin.URL == "" {
+			break
+		}
+
+		// Classic skins carry no metadata
+		model := models.SkinTypeClassic
+		if textures.Textures.Skin.Metadata.Model == "slim" {
+			model = models.SkinTypeSlim
+		}
+
+		return &Skin{URL: textures.Textures.Skin.URL, Model: model}, nil
+	}
+
+	return nil, models.ErrMojangSkinNotFound
+}
+
+// SkinURL resolves a Minecraft nickname to the URL of its current skin texture
+func (c *Client) SkinURL(name string) (string, error) {
+	skin, err := c.Skin(name)
+	if err != nil {
+		return "", err
+	}
+	return skin.URL, nil
+}
+
+func (c *Client) getJSON(u string, v any) error {
+	resp, err := c.HTTP.Get(u)
+	if err != nil {
+		return models.ErrSkinSourceUnreachable
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return models.ErrSkinSourceUnreachable
+		}
+		return nil
+	case http.StatusNoContent, http.StatusNotFound: // unknown nickname or uuid
+		return models.ErrMojangProfileNotFound
+	}
+
+	return models.ErrSkinSourceUnreachable
+}
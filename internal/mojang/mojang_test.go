@@ -0,0 +1,65 @@
+package mojang
+
+import (
+	"SkinRest/pkg/models"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// standIn answers like the Mojang API and session server for two players
+func standIn() *httptest.Server {
+	textures := func(skin string) string {
+		return base64.StdEncoding.EncodeToString([]byte(`{"timestamp":1,"textures":{"SKIN":` + skin + `}}`))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/users/profiles/minecraft/{name}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("name") {
+		case "Alex":
+			w.Write([]byte(`{"id":"a1","name":"Alex"}`))
+		case "Steve":
+			w.Write([]byte(`{"id":"s1","name":"Steve"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	mux.HandleFunc("/session/session/minecraft/profile/a1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"a1","name":"Alex","properties":[{"name":"textures","value":"` + textures(`{"url":"http://textures/alex","metadata":{"model":"slim"}}`) + `"}]}`))
+	})
+	mux.HandleFunc("/session/session/minecraft/profile/s1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"s1","name":"Steve","properties":[{"name":"textures","value":"` + textures(`{"url":"http://textures/steve"}`) + `"}]}`))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestSkin(t *testing.T) {
+	server := standIn()
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api", server.URL+"/session/", time.Second)
+
+	skin, err := client.Skin("Alex")
+	assert.NoError(t, err)
+	assert.Equal(t, &Skin{URL: "http://textures/alex", Model: models.SkinTypeSlim}, skin)
+
+	skin, err = client.Skin("Steve")
+	assert.NoError(t, err)
+	assert.Equal(t, &Skin{URL: "http://textures/steve", Model: models.SkinTypeClassic}, skin)
+
+	_, err = client.Skin("Nobody")
+	assert.Equal(t, models.ErrMojangProfileNotFound, err)
+}
+
+func TestValidName(t *testing.T) {
+	assert.True(t, ValidName("Notch"))
+	assert.True(t, ValidName("jeb_"))
+	assert.False(t, ValidName("ab"))
+	assert.False(t, ValidName("has space"))
+	assert.False(t, ValidName("seventeen_chars_x"))
+}
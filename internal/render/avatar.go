@@ -0,0 +1,43 @@
+package render
+
+import (
+	"image"
+	"image/draw"
+)
+
+const (
+	MinAvatarSize     int = 8
+	MaxAvatarSize     int = 512
+	DefaultAvatarSize int = 64
+)
+
+var (
+	faceRect = image.Rect(8, 8, 16, 16)  // head front, base layer
+	hatRect  = image.Rect(40, 8, 48, 16) // head front, overlay layer
+)
+
+// Avatar renders the face of a skin with the hat overlay composited on top,
+// scaled to size x size pixels with nearest-neighbour sampling.
+func Avatar(skin *image.NRGBA, size int) *image.NRGBA {
+	face := image.NewNRGBA(image.Rect(0, 0, faceRect.Dx(), faceRect.Dy()))
+	draw.Draw(face, face.Bounds(), skin, faceRect.Min, draw.Src)
+	draw.Draw(face, face.Bounds(), skin, hatRect.Min, draw.Over)
+
+	return scale(face, size, size)
+}
+
+// scale resizes src to w x h using nearest-neighbour sampling, keeping pixels crisp
+func scale(src *image.NRGBA, w, h int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+
+	for y := 0; y < h; y++ {
+		sy := y * sh / h
+		for x := 0; x < w; x++ {
+			sx := x * sw / w
+			dst.SetNRGBA(x, y, src.NRGBAAt(sx, sy))
+		}
+	}
+
+	return dst
+}
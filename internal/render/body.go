@@ -0,0 +1,232 @@
+package render
+
+import (
+	"SkinRest/pkg/models"
+	"image"
+	"image/color"
+	"sort"
+)
+
+const (
+	ViewFront string = "front"
+	ViewBack  string = "back"
+	ViewIso   string = "iso"
+
+	MinBodyScale     int = 1
+	MaxBodyScale     int = 32
+	DefaultBodyScale int = 8
+)
+
+// The renderer works in model space measured in skin pixels: x grows
+// towards the player's left (the viewer's right in the front view), y grows
+// downwards and z grows towards the front. Every part is an axis-aligned
+// box textured with Minecraft's box UV layout.
+//
+// All projection and sampling is done in integer arithmetic so the output is
+// bit-identical on every platform, which keeps snapshot tests stable.
+
+type vec3 struct{ x, y, z int }
+
+type part struct {
+	min     vec3 // corner with the smallest coordinates
+	size    vec3 // width, height, depth
+	base    image.Point
+	overlay image.Point
+}
+
+// face is one textured rectangle of a box: the texel (i, j) of tex covers the
+// model-space square starting at origin + i*a/tex.Dx() + j*b/tex.Dy().
+type face struct {
+	origin vec3
+	a, b   vec3
+	normal vec3
+	tex    image.Rectangle
+}
+
+type view struct {
+	camera  vec3                    // points from the model towards the viewer
+	project func(p vec3) (int, int) // model space to half-pixel screen units, before scaling
+}
+
+var views = map[string]view{
+	ViewFront: {
+		camera:  vec3{0, 0, 1},
+		project: func(p vec3) (int, int) { return 2 * p.x, 2 * p.y },
+	},
+	ViewBack: {
+		camera:  vec3{0, 0, -1},
+		project: func(p vec3) (int, int) { return -2 * p.x, 2 * p.y },
+	},
+	// 2:1 pixel-art isometric seen from the front, the player's left and above
+	ViewIso: {
+		camera:  vec3{1, -1, 1},
+		project: func(p vec3) (int, int) { return 2 * (p.x - p.z), 2*p.y + p.x + p.z },
+	},
+}
+
+// ValidView reports whether name is a supported body view
+func ValidView(name string) bool {
+	_, ok := views[name]
+	return ok
+}
+
+func bodyParts(model string) []part {
+	arm := 4
+	if model == models.SkinTypeSlim {
+		arm = 3
+	}
+
+	return []part{
+		{min: vec3{-4, 0, -4}, size: vec3{8, 8, 8}, base: image.Pt(0, 0), overlay: image.Pt(32, 0)},             // head
+		{min: vec3{-4, 8, -2}, size: vec3{8, 12, 4}, base: image.Pt(16, 16), overlay: image.Pt(16, 32)},         // body
+		{min: vec3{-4 - arm, 8, -2}, size: vec3{arm, 12, 4}, base: image.Pt(40, 16), overlay: image.Pt(40, 32)}, // right arm
+		{min: vec3{4, 8, -2}, size: vec3{arm, 12, 4}, base: image.Pt(32, 48), overlay: image.Pt(48, 48)},        // left arm
+		{min: vec3{-4, 20, -2}, size: vec3{4, 12, 4}, base: image.Pt(0, 16), overlay: image.Pt(0, 32)},          // right leg
+		{min: vec3{0, 20, -2}, size: vec3{4, 12, 4}, base: image.Pt(16, 48), overlay: image.Pt(0, 48)},          // left leg
+	}
+}
+
+// faces lays out the six faces of a box whose texture starts at uv
+func (p part) faces(uv image.Point) []face {
+	x0, y0, z0 := p.min.x, p.min.y, p.min.z
+	w, h, d := p.size.x, p.size.y, p.size.z
+	x1, y1, z1 := x0+w, y0+h, z0+d
+	u, v := uv.X, uv.Y
+
+	return []face{
+		{origin: vec3{x0, y0, z1}, a: vec3{w, 0, 0}, b: vec3{0, h, 0}, normal: vec3{0, 0, 1}, tex: image.Rect(u+d, v+d, u+d+w, v+d+h)},           // front
+		{origin: vec3{x1, y0, z0}, a: vec3{-w, 0, 0}, b: vec3{0, h, 0}, normal: vec3{0, 0, -1}, tex: image.Rect(u+2*d+w, v+d, u+2*d+2*w, v+d+h)}, // back
+		{origin: vec3{x0, y0, z0}, a: vec3{0, 0, d}, b: vec3{0, h, 0}, normal: vec3{-1, 0, 0}, tex: image.Rect(u, v+d, u+d, v+d+h)},              // right side
+		{origin: vec3{x1, y0, z1}, a: vec3{0, 0, -d}, b: vec3{0, h, 0}, normal: vec3{1, 0, 0}, tex: image.Rect(u+d+w, v+d, u+2*d+w, v+d+h)},      // left side
+		{origin: vec3{x0, y0, z0}, a: vec3{w, 0, 0}, b: vec3{0, 0, d}, normal: vec3{0, -1, 0}, tex: image.Rect(u+d, v, u+d+w, v+d)},              // top
+		{origin: vec3{x0, y1, z1}, a: vec3{w, 0, 0}, b: vec3{0, 0, -d}, normal: vec3{0, 1, 0}, tex: image.Rect(u+d+w, v, u+d+2*w, v+d)},          // bottom
+	}
+}
+
+// Body renders the whole player (head, body, arms and legs with their overlay
+// layer) from the given view, scale pixels per skin pixel. Legacy 64x32 skins
+// must be converted to the 64x64 layout first.
+func Body(skin *image.NRGBA, model, viewName string, scale int) *image.NRGBA {
+	v := views[viewName]
+	parts := bodyParts(model)
+
+	// painter's algorithm: parts further from the camera are drawn first,
+	// each part's overlay right after its base layer
+	sort.SliceStable(parts, func(i, j int) bool {
+		return dot(center2(parts[i]), v.camera) < dot(center2(parts[j]), v.camera)
+	})
+
+	// canvas covers the projection of every box corner
+	minU, minV := int(^uint(0)>>1), int(^uint(0)>>1)
+	maxU, maxV := -minU, -minV
+	for _, p := range parts {
+		for _, corner := range corners(p) {
+			u, w := v.project(corner)
+			minU, maxU = min(minU, u), max(maxU, u)
+			minV, maxV = min(minV, w), max(maxV, w)
+		}
+	}
+	minU, maxU, minV, maxV = minU*scale, maxU*scale, minV*scale, maxV*scale
+
+	dst := image.NewNRGBA(image.Rect(0, 0, (maxU-minU+1)/2, (maxV-minV+1)/2))
+
+	for _, p := range parts {
+		for _, uv := range []image.Point{p.base, p.overlay} {
+			for _, f := range p.faces(uv) {
+				if dot(f.normal, v.camera) <= 0 {
+					continue // facing away
+				}
+				drawFace(dst, skin, f, v, scale, minU, minV)
+			}
+		}
+	}
+
+	return dst
+}
+
+// drawFace fills every canvas pixel whose centre falls on the projected face
+// with the texel underneath it
+func drawFace(dst, skin *image.NRGBA, f face, v view, scale, offU, offV int) {
+	ou, ov := v.project(f.origin)
+	au, av := v.project(add(f.origin, f.a))
+	bu, bv := v.project(add(f.origin, f.b))
+	ou, ov = ou*scale-offU, ov*scale-offV
+	au, av = au*scale-offU-ou, av*scale-offV-ov
+	bu, bv = bu*scale-offU-ou, bv*scale-offV-ov
+
+	det := au*bv - av*bu
+	if det == 0 {
+		return // seen edge-on
+	}
+
+	// pixel range covered by the parallelogram, in half-pixel units
+	loU := min(0, au, bu, au+bu) + ou
+	hiU := max(0, au, bu, au+bu) + ou
+	loV := min(0, av, bv, av+bv) + ov
+	hiV := max(0, av, bv, av+bv) + ov
+
+	tw, th := f.tex.Dx(), f.tex.Dy()
+
+	for py := max(0, loV/2-1); py <= min(dst.Rect.Dy()-1, hiV/2); py++ {
+		for px := max(0, loU/2-1); px <= min(dst.Rect.Dx()-1, hiU/2); px++ {
+			du, dv := 2*px+1-ou, 2*py+1-ov
+
+			// solve d = s*a + t*b, with s = sn/det and t = tn/det
+			sn := du*bv - dv*bu
+			tn := au*dv - av*du
+			d := det
+			if d < 0 {
+				sn, tn, d = -sn, -tn, -d
+			}
+			if sn < 0 || sn >= d || tn < 0 || tn >= d {
+				continue
+			}
+
+			c := skin.NRGBAAt(f.tex.Min.X+sn*tw/d, f.tex.Min.Y+tn*th/d)
+			if c.A == 0 {
+				continue
+			}
+			dst.SetNRGBA(px, py, over(c, dst.NRGBAAt(px, py)))
+		}
+	}
+}
+
+// over composites src on top of dst
+func over(src, dst color.NRGBA) color.NRGBA {
+	if src.A == 255 || dst.A == 0 {
+		return src
+	}
+
+	sa, da := int(src.A), int(dst.A)*(255-int(src.A))/255
+	a := sa + da
+	mix := func(s, d uint8) uint8 {
+		return uint8((int(s)*sa + int(d)*da) / a)
+	}
+
+	return color.NRGBA{R: mix(src.R, dst.R), G: mix(src.G, dst.G), B: mix(src.B, dst.B), A: uint8(a)}
+}
+
+func corners(p part) []vec3 {
+	var cs []vec3
+	for _, dx := range []int{0, p.size.x} {
+		for _, dy := range []int{0, p.size.y} {
+			for _, dz := range []int{0, p.size.z} {
+				cs = append(cs, vec3{p.min.x + dx, p.min.y + dy, p.min.z + dz})
+			}
+		}
+	}
+	return cs
+}
+
+// center2 is twice the centre of a part, to stay in integers
+func center2(p part) vec3 {
+	return vec3{2*p.min.x + p.size.x, 2*p.min.y + p.size.y, 2*p.min.z + p.size.z}
+}
+
+func add(a, b vec3) vec3 {
+	return vec3{a.x + b.x, a.y + b.y, a.z + b.z}
+}
+
+func dot(a, b vec3) int {
+	return a.x*b.x + a.y*b.y + a.z*b.z
+}
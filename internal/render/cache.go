@@ -0,0 +1,158 @@
+package render
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Key identifies one rendered image. Renders only depend on the texture
+// pixels, so keying them by texture hash means a changed texture can never
+// be served a stale render.
+type Key struct {
+	Hash string // texture content hash
+	Kind string // e.g. "avatar" or "body-iso-slim"
+	Size int
+}
+
+// ETag returns a strong entity tag for the render
+func (k Key) ETag() string {
+	return fmt.Sprintf(`"%s-%s-%d"`, k.Hash, k.Kind, k.Size)
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("%s-%d", k.Kind, k.Size)
+}
+
+type entry struct {
+	key  Key
+	data []byte
+}
+
+// Cache keeps encoded renders in an in-memory LRU, optionally backed by a
+// directory on disk that survives restarts. It is safe for concurrent use;
+// a nil *Cache caches nothing.
+type Cache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is most recently used
+	entries  map[Key]*list.Element
+	dir      string // on-disk tier, disabled when empty
+}
+
+func NewCache(capacity int, dir string) *Cache {
+	return &Cache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[Key]*list.Element),
+		dir:      dir,
+	}
+}
+
+func (c *Cache) Get(k Key) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	if el, ok := c.entries[k]; ok {
+		c.order.MoveToFront(el)
+		data := el.Value.(*entry).data
+		c.mu.Unlock()
+		return data, true
+	}
+	c.mu.Unlock()
+
+	if c.dir == "" {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.path(k))
+	if err != nil {
+		return nil, false
+	}
+
+	c.remember(k, data)
+	return data, true
+}
+
+func (c *Cache) Put(k Key, data []byte) {
+	if c == nil {
+		return
+	}
+
+	c.remember(k, data)
+
+	if c.dir != "" {
+		c.writeFile(k, data) // the disk tier is best effort
+	}
+}
+
+// Invalidate drops every render of a texture from both tiers
+func (c *Cache) Invalidate(hash string) {
+	if c == nil || hash == "" {
+		return
+	}
+
+	c.mu.Lock()
+	for k, el := range c.entries {
+		if k.Hash == hash {
+			c.order.Remove(el)
+			delete(c.entries, k)
+		}
+	}
+	c.mu.Unlock()
+
+	if c.dir != "" {
+		os.RemoveAll(filepath.Join(c.dir, hash))
+	}
+}
+
+func (c *Cache) remember(k Key, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[k]; ok {
+		el.Value.(*entry).data = data
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[k] = c.order.PushFront(&entry{key: k, data: data})
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
+
+func (c *Cache) writeFile(k Key, data []byte) error {
+	dir := filepath.Join(c.dir, k.Hash)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".render-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path(k))
+}
+
+// path lays renders out as <dir>/<texture hash>/<kind>-<size>.png
+func (c *Cache) path(k Key) string {
+	return filepath.Join(c.dir, k.Hash, k.String()+".png")
+}
@@ -0,0 +1,153 @@
+package render
+
+import (
+	"SkinRest/pkg/models"
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAvatar(t *testing.T) {
+	skin := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	skin.Set(8, 8, color.NRGBA{R: 255, A: 255})
+	skin.Set(9, 8, color.NRGBA{R: 255, A: 255})
+	skin.Set(41, 8, color.NRGBA{B: 255, A: 255}) // hat covers the second face pixel
+
+	avatar := Avatar(skin, 16)
+	assert.Equal(t, image.Rect(0, 0, 16, 16), avatar.Bounds())
+	assert.Equal(t, color.NRGBA{R: 255, A: 255}, avatar.NRGBAAt(1, 1))
+	assert.Equal(t, color.NRGBA{B: 255, A: 255}, avatar.NRGBAAt(2, 1))
+	assert.Equal(t, color.NRGBA{B: 255, A: 255}, avatar.NRGBAAt(3, 0))
+}
+
+var update = flag.Bool("update", false, "rewrite golden render snapshots")
+
+// testSkin paints every texel with a colour derived from its position, so any
+// change in UV mapping or projection shows up in the snapshots
+func testSkin() *image.NRGBA {
+	skin := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			a := uint8(255)
+			if y < 16 && x >= 32 || y >= 32 && y < 48 || y >= 48 && (x < 16 || x >= 48) {
+				// overlay regions: sparse, so the base layer shows through
+				if (x+y)%3 != 0 {
+					continue
+				}
+				a = 200
+			}
+			skin.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 4), G: uint8(y * 4), B: uint8((x ^ y) * 4), A: a})
+		}
+	}
+	return skin
+}
+
+func TestBodySnapshots(t *testing.T) {
+	for _, model := range []string{models.SkinTypeClassic, models.SkinTypeSlim} {
+		for _, view := range []string{ViewFront, ViewBack, ViewIso} {
+			name := fmt.Sprintf("body_%s_%s.png", strings.ToLower(model), view)
+			t.Run(name, func(t *testing.T) {
+				got := Body(testSkin(), model, view, 4)
+				golden := filepath.Join("testdata", name)
+
+				if *update {
+					var buf bytes.Buffer
+					assert.NoError(t, png.Encode(&buf, got))
+					assert.NoError(t, os.MkdirAll("testdata", 0755))
+					assert.NoError(t, os.WriteFile(golden, buf.Bytes(), 0644))
+					return
+				}
+
+				data, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("missing snapshot, run go test -update: %v", err)
+				}
+				want, err := png.Decode(bytes.NewReader(data))
+				assert.NoError(t, err)
+
+				assert.Equal(t, want.Bounds(), got.Bounds())
+				assert.Equal(t, want.(*image.NRGBA).Pix, got.Pix)
+			})
+		}
+	}
+}
+
+func TestBodyFrontLayout(t *testing.T) {
+	got := Body(testSkin(), models.SkinTypeClassic, ViewFront, 1)
+	assert.Equal(t, image.Rect(0, 0, 16, 32), got.Bounds())
+
+	// second face pixel is texel (9, 8), hat texel (41, 8) is transparent there
+	assert.Equal(t, color.NRGBA{R: 36, G: 32, B: 4, A: 255}, got.NRGBAAt(5, 0))
+	// right arm front starts at texel (44, 20)
+	assert.Equal(t, color.NRGBA{R: 176, G: 80, B: uint8((44 ^ 20) * 4), A: 255}, got.NRGBAAt(0, 8))
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(2, dir)
+
+	a := Key{Hash: "aaaa", Kind: "avatar", Size: 64}
+	b := Key{Hash: "bbbb", Kind: "avatar", Size: 64}
+	c := Key{Hash: "bbbb", Kind: "body-front-classic", Size: 8}
+
+	cache.Put(a, []byte("a"))
+	cache.Put(b, []byte("b"))
+	cache.Put(c, []byte("c")) // evicts a from memory, the disk tier still has it
+
+	data, ok := cache.Get(a)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("a"), data)
+
+	cache.Invalidate("bbbb")
+	_, ok = cache.Get(b)
+	assert.False(t, ok)
+	_, ok = cache.Get(c)
+	assert.False(t, ok)
+
+	memoryOnly := NewCache(1, "")
+	memoryOnly.Put(a, []byte("a"))
+	memoryOnly.Put(b, []byte("b"))
+	_, ok = memoryOnly.Get(a)
+	assert.False(t, ok)
+
+	assert.Equal(t, `"aaaa-avatar-64"`, a.ETag())
+}
+
+func TestSourceHashes(t *testing.T) {
+	now := time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)
+	hashes := NewSourceHashes(2, time.Minute)
+	hashes.now = func() time.Time { return now }
+
+	hashes.Put("Notch", "aaaa")
+	hash, ok := hashes.Get("Notch")
+	assert.True(t, ok)
+	assert.Equal(t, "aaaa", hash)
+
+	now = now.Add(time.Minute) // the texture behind the source may have changed
+	_, ok = hashes.Get("Notch")
+	assert.False(t, ok)
+
+	hashes.Put("a", "1")
+	hashes.Put("b", "2") // the expired entry makes room
+	hashes.Put("c", "3") // full of live entries, start over
+	_, ok = hashes.Get("a")
+	assert.False(t, ok)
+	hash, ok = hashes.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, "3", hash)
+
+	var disabled *SourceHashes
+	disabled.Put("Notch", "aaaa")
+	_, ok = disabled.Get("Notch")
+	assert.False(t, ok)
+}
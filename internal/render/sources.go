@@ -0,0 +1,75 @@
+package render
+
+import (
+	"sync"
+	"time"
+)
+
+// SourceHashes remembers the texture hash of skin sources whose texture is
+// not stored, so serving a cached render or a 304 for them does not download
+// the source again. Entries expire after a TTL because the texture behind a
+// URL or a Mojang nickname can change. It is safe for concurrent use; a nil
+// *SourceHashes remembers nothing.
+type SourceHashes struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[string]sourceHash
+	now      func() time.Time
+}
+
+type sourceHash struct {
+	hash    string
+	expires time.Time
+}
+
+func NewSourceHashes(capacity int, ttl time.Duration) *SourceHashes {
+	return &SourceHashes{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]sourceHash),
+		now:      time.Now,
+	}
+}
+
+// Get returns the hash of the texture last downloaded from src, if it has not expired
+func (s *SourceHashes) Get(src string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[src]
+	if !ok || !s.now().Before(entry.expires) {
+		return "", false
+	}
+
+	return entry.hash, true
+}
+
+func (s *SourceHashes) Put(src, hash string) {
+	if s == nil || s.ttl <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	// drop expired entries when full, and everything if that is not enough
+	if len(s.entries) >= s.capacity {
+		for k, entry := range s.entries {
+			if !now.Before(entry.expires) {
+				delete(s.entries, k)
+			}
+		}
+		if len(s.entries) >= s.capacity {
+			clear(s.entries)
+		}
+	}
+
+	s.entries[src] = sourceHash{hash: hash, expires: now.Add(s.ttl)}
+}
@@ -0,0 +1,154 @@
+package skinsync
+
+import (
+	"SkinRest/internal/mojang"
+	"SkinRest/internal/texture"
+	"SkinRest/pkg/models"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Store is the part of database.AppContext the scheduler needs
+type Store interface {
+	GetNicknameSkins() ([]models.SkinData, error)
+	ResyncSkin(id int, previousKey string, texture *models.SkinTexture, skinType string) (bool, error)
+}
+
+// Scheduler keeps skins added by Mojang nickname in step with the skin the
+// player currently wears. Every interval it re-resolves each nickname,
+// downloads the texture and, when its hash differs from the stored one,
+// stores it and records the change in the skin's history. Nicknames are
+// resolved Delay apart, so a large run stays under Mojang's rate limit.
+type Scheduler struct {
+	Store    Store
+	Mojang   *mojang.Client
+	Fetcher  *texture.Fetcher
+	Logger   *zap.Logger
+	Interval time.Duration
+	Delay    time.Duration
+}
+
+// Run syncs right away, so a restart picks up changes at once, then every
+// interval until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	s.SyncAll(ctx)
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.SyncAll(ctx)
+		}
+	}
+}
+
+// resolved is the current skin of a nickname, shared by every row using it
+type resolved struct {
+	texture *models.SkinTexture
+	model   string
+	hash    string
+	err     error
+}
+
+// SyncAll syncs every nickname-sourced skin once and returns how many changed
+func (s *Scheduler) SyncAll(ctx context.Context) int {
+	skins, err := s.Store.GetNicknameSkins()
+	if err != nil {
+		s.Logger.Error("skin sync: " + err.Error())
+		return 0
+	}
+
+	changed := 0
+	seen := make(map[string]*resolved) // each nickname is resolved once per run
+
+	for _, skin := range skins {
+		if ctx.Err() != nil {
+			break
+		}
+
+		current, ok := seen[skin.Src]
+		if !ok {
+			if len(seen) > 0 && !s.wait(ctx) {
+				break
+			}
+			current = s.resolve(skin.Src)
+			seen[skin.Src] = current
+		}
+
+		if current.err != nil {
+			s.Logger.Warn("skin sync: " + skin.Src + ": " + current.err.Error())
+			continue
+		}
+
+		if current.hash == skin.Hash {
+			continue
+		}
+
+		updated, err := s.Store.ResyncSkin(skin.Id, skin.Hash, current.texture, current.model)
+		if err != nil {
+			s.Logger.Error("skin sync: " + err.Error())
+			continue
+		}
+		if updated {
+			changed++
+		}
+	}
+
+	s.Logger.Sugar().Infof("skin sync: %d of %d nickname skins changed", changed, len(skins))
+
+	return changed
+}
+
+// wait paces the Mojang lookups of a run, it reports false if ctx was cancelled meanwhile
+func (s *Scheduler) wait(ctx context.Context) bool {
+	if s.Delay <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(s.Delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (s *Scheduler) resolve(name string) *resolved {
+	mojangSkin, err := s.Mojang.Skin(name)
+	if err != nil {
+		return &resolved{err: err}
+	}
+
+	data, err := s.Fetcher.Fetch(mojangSkin.URL)
+	if err != nil {
+		return &resolved{err: err}
+	}
+
+	img, err := texture.DecodeSkin(data)
+	if err != nil {
+		return &resolved{err: err}
+	}
+
+	skinTexture, err := texture.PrepareSkin(img, data)
+	if err != nil {
+		return &resolved{err: err}
+	}
+	skinTexture.SourceURL = mojangSkin.URL
+
+	// blob keys are the hash of the stored, converted texture
+	hash, err := texture.HashPNG(skinTexture.Data)
+	if err != nil {
+		return &resolved{err: err}
+	}
+
+	return &resolved{texture: skinTexture, model: mojangSkin.Model, hash: hash}
+}
@@ -0,0 +1,148 @@
+package skinsync
+
+import (
+	"SkinRest/internal/mojang"
+	"SkinRest/internal/texture"
+	"SkinRest/pkg/models"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type resync struct {
+	id          int
+	previousKey string
+	texture     *models.SkinTexture
+	skinType    string
+}
+
+type fakeStore struct {
+	skins   []models.SkinData
+	resyncs []resync
+	listed  int // GetNicknameSkins calls
+}
+
+func (f *fakeStore) GetNicknameSkins() ([]models.SkinData, error) {
+	f.listed++
+	return f.skins, nil
+}
+
+func (f *fakeStore) ResyncSkin(id int, previousKey string, texture *models.SkinTexture, skinType string) (bool, error) {
+	f.resyncs = append(f.resyncs, resync{id, previousKey, texture, skinType})
+	return true, nil
+}
+
+func TestSyncAll(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	img.Set(8, 8, color.NRGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	skin := buf.Bytes()
+	hash, err := texture.HashPNG(skin)
+	assert.NoError(t, err)
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/users/profiles/minecraft/Alex", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"a1","name":"Alex"}`))
+	})
+	mux.HandleFunc("/session/session/minecraft/profile/a1", func(w http.ResponseWriter, r *http.Request) {
+		value := base64.StdEncoding.EncodeToString([]byte(`{"textures":{"SKIN":{"url":"` + server.URL + `/alex.png","metadata":{"model":"slim"}}}}`))
+		w.Write([]byte(`{"id":"a1","name":"Alex","properties":[{"name":"textures","value":"` + value + `"}]}`))
+	})
+	mux.HandleFunc("/alex.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(skin)
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	store := &fakeStore{skins: []models.SkinData{
+		{Id: 1, Src: "Alex", Hash: "stale"},
+		{Id: 2, Src: "Alex", Hash: hash}, // already current
+		{Id: 3, Src: "Nobody"},
+	}}
+
+	s := &Scheduler{
+		Store:   store,
+		Mojang:  mojang.NewClient(server.URL+"/api", server.URL+"/session", time.Second),
+		Fetcher: texture.NewFetcher(time.Second, 3, true),
+		Logger:  zap.NewNop(),
+	}
+
+	assert.Equal(t, 1, s.SyncAll(context.Background()))
+	assert.Len(t, store.resyncs, 1)
+	assert.Equal(t, 1, store.resyncs[0].id)
+	assert.Equal(t, "stale", store.resyncs[0].previousKey)
+	assert.Equal(t, models.SkinTypeSlim, store.resyncs[0].skinType)
+	assert.Equal(t, skin, store.resyncs[0].texture.Data)
+	assert.Equal(t, server.URL+"/alex.png", store.resyncs[0].texture.SourceURL)
+}
+
+func TestRunSyncsAtStartup(t *testing.T) {
+	store := &fakeStore{}
+	s := &Scheduler{Store: store, Logger: zap.NewNop(), Interval: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s.Run(ctx) // returns once cancelled, without waiting for the interval
+	assert.Equal(t, 1, store.listed)
+}
+
+func TestSyncAllPacesLookups(t *testing.T) {
+	var lookups []time.Time
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups = append(lookups, time.Now())
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	store := &fakeStore{skins: []models.SkinData{
+		{Id: 1, Src: "Alex"},
+		{Id: 2, Src: "Alex"}, // resolved once for both rows
+		{Id: 3, Src: "Steve"},
+		{Id: 4, Src: "Notch"},
+	}}
+
+	s := &Scheduler{
+		Store:   store,
+		Mojang:  mojang.NewClient(server.URL+"/api", server.URL+"/session", time.Second),
+		Fetcher: texture.NewFetcher(time.Second, 3, true),
+		Logger:  zap.NewNop(),
+		Delay:   50 * time.Millisecond,
+	}
+
+	s.SyncAll(ctx)
+	if assert.Len(t, lookups, 3) {
+		for i := 1; i < len(lookups); i++ {
+			assert.GreaterOrEqual(t, lookups[i].Sub(lookups[i-1]), s.Delay)
+		}
+	}
+
+	// a cancelled run stops waiting at once
+	lookups = nil
+	s.Delay = time.Hour
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	s.SyncAll(ctx)
+	assert.Len(t, lookups, 1)
+	assert.Less(t, time.Since(start), time.Minute)
+}
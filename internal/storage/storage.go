@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"SkinRest/config"
+	"fmt"
+)
+
+// BlobStore keeps opaque binary objects (skin textures) addressed by key.
+// Rows only reference keys, so the backend can be swapped without touching them.
+type BlobStore interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error) // returns models.ErrBlobNotFound if the key is unknown
+	Delete(key string) error        // deleting a missing key is not an error
+}
+
+// New returns the blob store selected in the storage configuration
+func New(cfg *config.Config) (BlobStore, error) {
+	switch cfg.Storage.Driver {
+	case "local":
+		dir := cfg.Storage.LocalDir
+		if dir == "" {
+			if cfg.Server.ApiEnv == "local" { // set local or release path to textures
+				dir = "textures"
+			} else {
+				dir = "/root/textures"
+			}
+		}
+		return NewLocalStore(dir), nil
+
+	case "s3":
+		if cfg.Storage.S3Endpoint == "" || cfg.Storage.S3Bucket == "" {
+			return nil, fmt.Errorf("storage: s3 driver requires STORAGE_S3_ENDPOINT and STORAGE_S3_BUCKET")
+		}
+		return NewS3Store(cfg.Storage.S3Endpoint, cfg.Storage.S3Region, cfg.Storage.S3Bucket, cfg.Storage.S3AccessKey, cfg.Storage.S3SecretKey), nil
+	}
+
+	return nil, fmt.Errorf("storage: unknown driver %q", cfg.Storage.Driver)
+}
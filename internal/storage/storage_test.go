@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"SkinRest/pkg/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is a minimal MinIO-like stand-in: one bucket, path-style keys, SigV4 checked.
+type fakeS3 struct {
+	signer  *S3Store
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	// re-sign the request as received and compare, as the real service would
+	check, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.EscapedPath(), nil)
+	f.signer.now = func() time.Time {
+		t, _ := time.Parse(s3TimeFormat, r.Header.Get("X-Amz-Date"))
+		return t
+	}
+	f.signer.sign(check, body)
+	if check.Header.Get("Authorization") != r.Header.Get("Authorization") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/skins/")
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = body
+	case http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func testBlobStore(t *testing.T, store BlobStore) {
+	assert.NoError(t, store.Put("abc123", []byte("texture")))
+
+	data, err := store.Get("abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("texture"), data)
+
+	assert.NoError(t, store.Delete("abc123"))
+	assert.NoError(t, store.Delete("abc123"))
+
+	_, err = store.Get("abc123")
+	assert.Equal(t, models.ErrBlobNotFound, err)
+}
+
+func TestLocalStore(t *testing.T) {
+	store := NewLocalStore(t.TempDir())
+	testBlobStore(t, store)
+
+	assert.Equal(t, models.ErrInvalidBlobKey, store.Put("../escape", []byte("x")))
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	fake.signer = NewS3Store(server.URL, "us-east-1", "skins", "minio", "minio-secret")
+	testBlobStore(t, NewS3Store(server.URL, "us-east-1", "skins", "minio", "minio-secret"))
+
+	wrongSecret := NewS3Store(server.URL, "us-east-1", "skins", "minio", "not-the-secret")
+	assert.Error(t, wrongSecret.Put("abc123", []byte("texture")))
+}
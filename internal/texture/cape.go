@@ -0,0 +1,35 @@
+package texture
+
+import (
+	"SkinRest/pkg/models"
+	"image"
+)
+
+const (
+	CapeWidth  int = 64
+	CapeHeight int = 32
+)
+
+// elytraRegion is the part of a cape texture the game wraps around elytra
+var elytraRegion = image.Rect(22, 0, 46, 22)
+
+// DecodeCape checks that data is a 64x32 PNG cape with an alpha channel,
+// and returns it as NRGBA.
+func DecodeCape(data []byte) (*image.NRGBA, error) {
+	return decode(data, func(width, height int) bool {
+		return width == CapeWidth && height == CapeHeight
+	}, models.ErrInvalidCapeTexture, models.ErrInvalidCapeSize, models.ErrInvalidCapeColor)
+}
+
+// HasElytra reports whether the elytra region of a cape has any visible
+// pixel. Capes without one make elytra invisible in game.
+func HasElytra(img *image.NRGBA) bool {
+	for y := elytraRegion.Min.Y; y < elytraRegion.Max.Y; y++ {
+		for x := elytraRegion.Min.X; x < elytraRegion.Max.X; x++ {
+			if img.NRGBAAt(x, y).A != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
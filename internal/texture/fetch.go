@@ -0,0 +1,148 @@
+package texture
+
+import (
+	"SkinRest/pkg/models"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// pngMagic starts every PNG file
+var pngMagic = []byte("\x89PNG\r\n\x1a\n")
+
+// blockedPrefixes are ranges outside what netip classifies as private,
+// loopback or link-local that still must not be reachable from a source URL
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, includes broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, can embed any IPv4 address
+}
+
+var errBlockedAddress = errors.New("texture: source resolves to a blocked address")
+
+// IsURL reports whether a skin source is a link rather than a Mojang nickname
+func IsURL(src string) bool {
+	u, err := url.Parse(src)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Fetcher downloads textures from user supplied URLs. Every connection,
+// redirects included, is checked against a blocklist of private, loopback
+// and link-local addresses after DNS resolution, so a source cannot make
+// the server reach into its own network.
+type Fetcher struct {
+	client *http.Client
+}
+
+// NewFetcher returns a fetcher giving up after timeout and maxRedirects
+// redirects. allowPrivate lifts the address blocklist, for tests and
+// deployments that fetch from their own network on purpose.
+func NewFetcher(timeout time.Duration, maxRedirects int, allowPrivate bool) *Fetcher {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || blockedAddr(addrPort.Addr()) {
+				return errBlockedAddress
+			}
+			return nil
+		}
+	}
+
+	return &Fetcher{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:                 nil, // a proxy would connect on our behalf and bypass the blocklist
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
+				MaxIdleConns:          10,
+				IdleConnTimeout:       time.Minute,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > maxRedirects {
+					return fmt.Errorf("texture: more than %d redirects", maxRedirects)
+				}
+				if !IsURL(req.URL.String()) {
+					return fmt.Errorf("texture: redirect to unsupported URL %q", req.URL.Redacted())
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// Fetch downloads the PNG texture a URL skin source points to. The response
+// must be declared as image/png, start with the PNG signature and stay
+// within MaxTextureSize.
+func (f *Fetcher) Fetch(src string) ([]byte, error) {
+	if !IsURL(src) {
+		return nil, models.ErrInvalidSkinSource
+	}
+
+	resp, err := f.client.Get(src)
+	if err != nil {
+		if errors.Is(err, errBlockedAddress) {
+			return nil, models.ErrSkinSourceForbidden
+		}
+		return nil, models.ErrSkinSourceUnreachable
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, models.ErrSkinSourceUnreachable
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err != nil || mediaType != "image/png" {
+		return nil, models.ErrSkinSourceNotPNG
+	}
+
+	if resp.ContentLength > MaxTextureSize {
+		return nil, models.ErrSkinTextureTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxTextureSize+1))
+	if err != nil {
+		return nil, models.ErrSkinSourceUnreachable
+	}
+
+	if int64(len(data)) > MaxTextureSize {
+		return nil, models.ErrSkinTextureTooLarge
+	}
+
+	if !bytes.HasPrefix(data, pngMagic) {
+		return nil, models.ErrSkinSourceNotPNG
+	}
+
+	return data, nil
+}
+
+func blockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
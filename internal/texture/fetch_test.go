@@ -0,0 +1,67 @@
+package texture
+
+import (
+	"SkinRest/pkg/models"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetcher(t *testing.T) {
+	skin := encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 64, 64)))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/skin.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(skin)
+	})
+	mux.HandleFunc("/page.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	})
+	mux.HandleFunc("/fake.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("GIF89a"))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// the test server listens on loopback, which is blocked by default
+	_, err := NewFetcher(time.Second, 3, false).Fetch(server.URL + "/skin.png")
+	assert.Equal(t, models.ErrSkinSourceForbidden, err)
+
+	fetcher := NewFetcher(time.Second, 3, true)
+
+	data, err := fetcher.Fetch(server.URL + "/skin.png")
+	assert.NoError(t, err)
+	assert.Equal(t, skin, data)
+
+	_, err = fetcher.Fetch(server.URL + "/page.html")
+	assert.Equal(t, models.ErrSkinSourceNotPNG, err)
+
+	_, err = fetcher.Fetch(server.URL + "/fake.png")
+	assert.Equal(t, models.ErrSkinSourceNotPNG, err)
+
+	_, err = fetcher.Fetch(server.URL + "/loop")
+	assert.Equal(t, models.ErrSkinSourceUnreachable, err)
+
+	_, err = fetcher.Fetch("file:///etc/passwd")
+	assert.Equal(t, models.ErrInvalidSkinSource, err)
+}
+
+func TestBlockedAddr(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "169.254.169.254", "::1", "fe80::1", "::ffff:127.0.0.1", "100.64.0.1", "0.0.0.0"} {
+		assert.True(t, blockedAddr(netip.MustParseAddr(addr)), addr)
+	}
+	for _, addr := range []string{"8.8.8.8", "2606:4700::1111"} {
+		assert.False(t, blockedAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
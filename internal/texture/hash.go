@@ -0,0 +1,46 @@
+package texture
+
+import (
+	"SkinRest/pkg/models"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"image"
+	"image/png"
+)
+
+// Hash returns the content hash of a texture the same way Mojang and
+// authlib-injector compute texture hashes: SHA-256 over the big-endian
+// width and height followed by every pixel as ARGB, column by column,
+// with fully transparent pixels zeroed. Two PNGs with identical pixels
+// therefore share a hash regardless of how they were encoded.
+func Hash(img *image.NRGBA) string {
+	b := img.Bounds()
+	buf := make([]byte, 8, 8+b.Dx()*b.Dy()*4)
+	binary.BigEndian.PutUint32(buf[0:4], uint32(b.Dx()))
+	binary.BigEndian.PutUint32(buf[4:8], uint32(b.Dy()))
+
+	for x := b.Min.X; x < b.Max.X; x++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			c := img.NRGBAAt(x, y)
+			if c.A == 0 {
+				buf = append(buf, 0, 0, 0, 0)
+			} else {
+				buf = append(buf, c.A, c.R, c.G, c.B)
+			}
+		}
+	}
+
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashPNG decodes PNG data and returns its content hash
+func HashPNG(data []byte) (string, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", models.ErrInvalidSkinTexture
+	}
+	return Hash(toNRGBA(img)), nil
+}
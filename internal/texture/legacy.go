@@ -0,0 +1,66 @@
+package texture
+
+import (
+	"SkinRest/pkg/models"
+	"image"
+	"image/draw"
+)
+
+// legacyLimbFaces maps each face of the right leg and right arm onto the
+// left-limb slot of the 1.8 layout. The left limbs are the mirror image of
+// the right ones, so every face is flipped horizontally and the inner and
+// outer sides swap places. Values are x, y, width, height, target x, target y.
+var legacyLimbFaces = [][6]int{
+	// leg
+	{4, 16, 4, 4, 20, 48},   // top
+	{8, 16, 4, 4, 24, 48},   // bottom
+	{0, 20, 4, 12, 24, 52},  // outer -> inner
+	{4, 20, 4, 12, 20, 52},  // front
+	{8, 20, 4, 12, 16, 52},  // inner -> outer
+	{12, 20, 4, 12, 28, 52}, // back
+	// arm
+	{44, 16, 4, 4, 36, 48},  // top
+	{48, 16, 4, 4, 40, 48},  // bottom
+	{40, 20, 4, 12, 40, 52}, // outer -> inner
+	{44, 20, 4, 12, 36, 52}, // front
+	{48, 20, 4, 12, 32, 52}, // inner -> outer
+	{52, 20, 4, 12, 44, 52}, // back
+}
+
+// ConvertLegacy upgrades a 64x32 skin to the 64x64 layout, filling the
+// left arm and leg with mirrored copies of the right ones. Skins that are
+// already 64x64 are returned unchanged.
+func ConvertLegacy(img *image.NRGBA) *image.NRGBA {
+	if !IsLegacy(img) {
+		return img
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, SkinWidth, SkinHeight))
+	draw.Draw(dst, img.Bounds(), img, image.Point{}, draw.Src)
+
+	for _, f := range legacyLimbFaces {
+		sx, sy, w, h, dx, dy := f[0], f[1], f[2], f[3], f[4], f[5]
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				dst.SetNRGBA(dx+w-1-x, dy+y, img.NRGBAAt(sx+x, sy+y))
+			}
+		}
+	}
+
+	return dst
+}
+
+// PrepareSkin prepares a decoded skin for storage, converting 64x32 textures
+// to the 64x64 layout and keeping data as the original
+func PrepareSkin(img *image.NRGBA, data []byte) (*models.SkinTexture, error) {
+	if !IsLegacy(img) {
+		return &models.SkinTexture{Data: data}, nil
+	}
+
+	converted, err := Encode(ConvertLegacy(img))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SkinTexture{Data: converted, Original: data}, nil
+}
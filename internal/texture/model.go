@@ -0,0 +1,33 @@
+package texture
+
+import (
+	"SkinRest/pkg/models"
+	"image"
+)
+
+// Columns that only Classic skins paint: the outermost back column pair of
+// each arm. Slim arms are 3px wide, so on Slim skins these are transparent.
+var slimArmGaps = []image.Rectangle{
+	image.Rect(54, 20, 56, 32), // right arm
+	image.Rect(46, 52, 48, 64), // left arm
+}
+
+// DetectModel infers the arm model a skin texture was drawn for.
+// Legacy 64x32 skins predate Slim arms and are always Classic.
+func DetectModel(img *image.NRGBA) string {
+	if IsLegacy(img) {
+		return models.SkinTypeClassic
+	}
+
+	for _, r := range slimArmGaps {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if img.NRGBAAt(x, y).A != 0 {
+					return models.SkinTypeClassic
+				}
+			}
+		}
+	}
+
+	return models.SkinTypeSlim
+}
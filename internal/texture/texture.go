@@ -0,0 +1,87 @@
+package texture
+
+import (
+	"SkinRest/pkg/models"
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+const (
+	SkinWidth        int = 64
+	SkinHeight       int = 64
+	LegacySkinHeight int = 32
+
+	MaxTextureSize int64 = 1 << 20 // 1 MiB is far more than any real skin needs
+)
+
+// DecodeSkin checks that data is a PNG laid out as a Minecraft skin
+// (64x64, or legacy 64x32) with an alpha channel, and returns it as NRGBA.
+func DecodeSkin(data []byte) (*image.NRGBA, error) {
+	return decode(data, func(width, height int) bool {
+		return width == SkinWidth && (height == SkinHeight || height == LegacySkinHeight)
+	}, models.ErrInvalidSkinTexture, models.ErrInvalidSkinSize, models.ErrInvalidSkinColor)
+}
+
+// decode checks the format, size and colour model of a PNG texture before
+// decoding it, answering with the given errors
+func decode(data []byte, validSize func(width, height int) bool, errFormat, errSize, errColor error) (*image.NRGBA, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || format != "png" {
+		return nil, errFormat
+	}
+
+	if !validSize(cfg.Width, cfg.Height) {
+		return nil, errSize
+	}
+
+	if !hasAlpha(cfg.ColorModel) {
+		return nil, errColor
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errFormat
+	}
+
+	return toNRGBA(img), nil
+}
+
+// IsLegacy reports whether img uses the pre-1.8 64x32 layout.
+func IsLegacy(img image.Image) bool {
+	return img.Bounds().Dy() == LegacySkinHeight
+}
+
+// Encode writes img as PNG.
+func Encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// hasAlpha reports whether the PNG colour type carries transparency.
+// The standard decoder reports truecolour and greyscale without alpha as
+// RGBA/Gray models, and truecolour with alpha as NRGBA.
+func hasAlpha(m color.Model) bool {
+	switch m {
+	case color.NRGBAModel, color.NRGBA64Model:
+		return true
+	}
+	_, paletted := m.(color.Palette)
+	return paletted
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba
+	}
+
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,113 @@
+package texture
+
+import (
+	"SkinRest/pkg/models"
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeSkin(t *testing.T) {
+	modern := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	modern.Set(8, 8, color.NRGBA{R: 255, A: 255})
+
+	img, err := DecodeSkin(encodePNG(t, modern))
+	assert.NoError(t, err)
+	assert.False(t, IsLegacy(img))
+	assert.Equal(t, color.NRGBA{R: 255, A: 255}, img.NRGBAAt(8, 8))
+
+	legacy, err := DecodeSkin(encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 64, 32))))
+	assert.NoError(t, err)
+	assert.True(t, IsLegacy(legacy))
+}
+
+func TestDecodeSkinRejects(t *testing.T) {
+	_, err := DecodeSkin([]byte("definitely not a png"))
+	assert.Equal(t, models.ErrInvalidSkinTexture, err)
+
+	_, err = DecodeSkin(encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 128, 128))))
+	assert.Equal(t, models.ErrInvalidSkinSize, err)
+
+	opaque := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for i := 3; i < len(opaque.Pix); i += 4 {
+		opaque.Pix[i] = 255 // fully opaque, so the encoder drops the alpha channel
+	}
+	_, err = DecodeSkin(encodePNG(t, opaque))
+	assert.Equal(t, models.ErrInvalidSkinColor, err)
+}
+
+func TestHashIgnoresEncoding(t *testing.T) {
+	a := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	a.Set(1, 2, color.NRGBA{R: 10, G: 20, B: 30, A: 255})
+	a.Set(3, 4, color.NRGBA{R: 99, A: 0}) // invisible colour must not matter
+
+	b := image.NewPaletted(image.Rect(0, 0, 64, 64), color.Palette{color.NRGBA{}, color.NRGBA{R: 10, G: 20, B: 30, A: 255}})
+	b.SetColorIndex(1, 2, 1)
+
+	hashA, err := HashPNG(encodePNG(t, a))
+	assert.NoError(t, err)
+	hashB, err := HashPNG(encodePNG(t, b))
+	assert.NoError(t, err)
+
+	assert.Equal(t, hashA, hashB)
+	assert.Len(t, hashA, 64)
+}
+
+func TestDetectModel(t *testing.T) {
+	slim := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 20; y < 32; y++ {
+		for x := 40; x < 54; x++ { // 3px wide right arm stops before column 54
+			slim.Set(x, y, color.NRGBA{G: 200, A: 255})
+		}
+	}
+	assert.Equal(t, models.SkinTypeSlim, DetectModel(slim))
+
+	classic := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	classic.Set(47, 60, color.NRGBA{B: 200, A: 255})
+	assert.Equal(t, models.SkinTypeClassic, DetectModel(classic))
+
+	assert.Equal(t, models.SkinTypeClassic, DetectModel(image.NewNRGBA(image.Rect(0, 0, 64, 32))))
+}
+
+func TestConvertLegacy(t *testing.T) {
+	legacy := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	legacy.Set(8, 8, color.NRGBA{R: 1, A: 255})   // face
+	legacy.Set(4, 20, color.NRGBA{G: 2, A: 255})  // right leg front, left column
+	legacy.Set(44, 20, color.NRGBA{B: 3, A: 255}) // right arm front, left column
+
+	modern := ConvertLegacy(legacy)
+	assert.Equal(t, image.Rect(0, 0, 64, 64), modern.Bounds())
+	assert.Equal(t, color.NRGBA{R: 1, A: 255}, modern.NRGBAAt(8, 8))
+	assert.Equal(t, color.NRGBA{G: 2, A: 255}, modern.NRGBAAt(23, 52)) // mirrored into the right column
+	assert.Equal(t, color.NRGBA{B: 3, A: 255}, modern.NRGBAAt(39, 52))
+	assert.False(t, IsLegacy(modern))
+}
+
+func TestDecodeCape(t *testing.T) {
+	cape := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	cape.Set(1, 1, color.NRGBA{R: 200, A: 255})
+
+	img, err := DecodeCape(encodePNG(t, cape))
+	assert.NoError(t, err)
+	assert.False(t, HasElytra(img))
+
+	cape.Set(30, 10, color.NRGBA{G: 200, A: 255})
+	img, err = DecodeCape(encodePNG(t, cape))
+	assert.NoError(t, err)
+	assert.True(t, HasElytra(img))
+
+	_, err = DecodeCape(encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 64, 64))))
+	assert.Equal(t, models.ErrInvalidCapeSize, err)
+}
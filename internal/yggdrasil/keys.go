@@ -0,0 +1,99 @@
+package yggdrasil
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const keyBits int = 4096
+
+// Signer signs profile properties with the server's RSA key. Game clients
+// verify the signature of the "textures" property against the public key
+// advertised in the API metadata.
+type Signer struct {
+	key *rsa.PrivateKey
+}
+
+func NewSigner(key *rsa.PrivateKey) *Signer {
+	return &Signer{key: key}
+}
+
+// LoadOrCreateSigner reads a PEM encoded RSA private key from path, generating
+// and saving a new one on first start so the key survives restarts
+func LoadOrCreateSigner(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("yggdrasil: %s does not contain a PEM key", path)
+		}
+
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		key, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("yggdrasil: %s is not an RSA key", path)
+		}
+
+		return NewSigner(key), nil
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, err
+	}
+
+	return NewSigner(key), nil
+}
+
+// Sign returns the base64 SHA1withRSA signature of a property value
+func (s *Signer) Sign(value string) (string, error) {
+	digest := sha1.Sum([]byte(value))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA1, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// PublicKeyPEM returns the public key in the format expected by authlib-injector
+func (s *Signer) PublicKeyPEM() (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(&s.key.PublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
@@ -0,0 +1,69 @@
+package yggdrasil
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// OfflineUUID returns the UUID an offline-mode server assigns to a player
+// name (a version 3 UUID of "OfflinePlayer:<name>"), without dashes as the
+// Yggdrasil API writes it. Using it as the profile id keeps players' UUIDs
+// unchanged when a server switches to SkinRest.
+func OfflineUUID(name string) string {
+	sum := md5.Sum([]byte("OfflinePlayer:" + name))
+	sum[6] = sum[6]&0x0f | 0x30 // version 3
+	sum[8] = sum[8]&0x3f | 0x80 // RFC 4122 variant
+	return hex.EncodeToString(sum[:])
+}
+
+// RandomUUID returns a random (version 4) UUID without dashes, for players
+// whose offline UUID is already taken
+func RandomUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return hex.EncodeToString(b[:]), nil
+}
+
+// ProfileRef is the short profile form returned by name lookups
+type ProfileRef struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Property struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// Profile is the full profile form returned by the session server
+type Profile struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Properties []Property `json:"properties"`
+}
+
+// Textures is the decoded value of the "textures" property
+type Textures struct {
+	Timestamp   int64              `json:"timestamp"`
+	ProfileId   string             `json:"profileId"`
+	ProfileName string             `json:"profileName"`
+	Textures    map[string]Texture `json:"textures"`
+}
+
+type Texture struct {
+	URL      string            `json:"url"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Metadata is served at the API root and tells authlib-injector about the server
+type Metadata struct {
+	Meta               map[string]any `json:"meta"`
+	SkinDomains        []string       `json:"skinDomains"`
+	SignaturePublickey string         `json:"signaturePublickey"`
+}
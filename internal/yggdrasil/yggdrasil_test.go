@@ -0,0 +1,64 @@
+package yggdrasil
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfflineUUID(t *testing.T) {
+	// the UUID a vanilla offline-mode server gives this player
+	assert.Equal(t, "b50ad385829d3141a2167e7d7539ba7f", OfflineUUID("Notch"))
+}
+
+func TestRandomUUID(t *testing.T) {
+	a, err := RandomUUID()
+	assert.NoError(t, err)
+	b, err := RandomUUID()
+	assert.NoError(t, err)
+
+	assert.Len(t, a, 32)
+	assert.Equal(t, byte('4'), a[12]) // version nibble
+	assert.NotEqual(t, a, b)
+}
+
+func TestSigner(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	signer := NewSigner(key)
+
+	signature, err := signer.Sign("value")
+	assert.NoError(t, err)
+
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	assert.NoError(t, err)
+
+	publicPEM, err := signer.PublicKeyPEM()
+	assert.NoError(t, err)
+	block, _ := pem.Decode([]byte(publicPEM))
+	assert.Equal(t, "PUBLIC KEY", block.Type)
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	assert.NoError(t, err)
+
+	digest := sha1.Sum([]byte("value"))
+	assert.NoError(t, rsa.VerifyPKCS1v15(public.(*rsa.PublicKey), crypto.SHA1, digest[:], raw))
+}
+
+func TestLoadOrCreateSigner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "yggdrasil.pem")
+
+	created, err := LoadOrCreateSigner(path)
+	assert.NoError(t, err)
+
+	loaded, err := LoadOrCreateSigner(path)
+	assert.NoError(t, err)
+	assert.True(t, created.key.Equal(loaded.key))
+}
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE skinstable ADD COLUMN IF NOT EXISTS skin_texture VARCHAR(64) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE skinstable DROP COLUMN IF EXISTS skin_texture;
+-- +goose StatementEnd
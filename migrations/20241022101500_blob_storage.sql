@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- textures written to the local directory keep their file names as blob keys
+ALTER TABLE skinstable RENAME COLUMN skin_texture TO blob_key;
+ALTER TABLE skinstable ALTER COLUMN blob_key TYPE VARCHAR(255);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE skinstable ALTER COLUMN blob_key TYPE VARCHAR(64);
+ALTER TABLE skinstable RENAME COLUMN blob_key TO skin_texture;
+-- +goose StatementEnd
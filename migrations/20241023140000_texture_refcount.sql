@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS texturestable (
+        blob_key VARCHAR(255) PRIMARY KEY,
+        ref_count INT NOT NULL DEFAULT 0
+    );
+
+-- count references to textures stored before deduplication
+INSERT INTO texturestable (blob_key, ref_count)
+SELECT blob_key, COUNT(*) FROM skinstable WHERE blob_key <> '' GROUP BY blob_key
+ON CONFLICT (blob_key) DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS texturestable;
+-- +goose StatementEnd
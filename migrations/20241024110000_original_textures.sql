@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE skinstable ADD COLUMN IF NOT EXISTS original_blob_key VARCHAR(255) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE skinstable DROP COLUMN IF EXISTS original_blob_key;
+-- +goose StatementEnd
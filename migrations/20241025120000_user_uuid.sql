@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE userstable ADD COLUMN IF NOT EXISTS user_uuid CHAR(32);
+
+-- Offline-mode UUID: version 3 UUID of md5('OfflinePlayer:' || login)
+UPDATE userstable SET user_uuid = substr(h, 1, 12) || '3' || substr(h, 14, 3)
+        || substr('89ab', (('x' || substr(h, 17, 1))::bit(4)::int & 3) + 1, 1) || substr(h, 18, 15)
+    FROM (SELECT user_id AS id, md5('OfflinePlayer:' || login) AS h FROM userstable) AS hashes
+    WHERE user_id = hashes.id AND user_uuid IS NULL;
+
+ALTER TABLE userstable ALTER COLUMN user_uuid SET NOT NULL;
+ALTER TABLE userstable DROP CONSTRAINT IF EXISTS userstable_user_uuid_key;
+ALTER TABLE userstable ADD CONSTRAINT userstable_user_uuid_key UNIQUE (user_uuid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE userstable DROP COLUMN IF EXISTS user_uuid;
+-- +goose StatementEnd
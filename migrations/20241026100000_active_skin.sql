@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE userstable ADD COLUMN IF NOT EXISTS active_skin_id INT;
+
+-- Keep serving the skin the Yggdrasil API picked so far: the latest one with a stored texture
+UPDATE userstable SET active_skin_id = (
+        SELECT skin_id FROM skinstable
+        WHERE owner_name = userstable.login AND blob_key <> ''
+        ORDER BY skin_id DESC LIMIT 1
+    )
+    WHERE active_skin_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE userstable DROP COLUMN IF EXISTS active_skin_id;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS capestable (
+    cape_id SERIAL PRIMARY KEY,
+    owner_name VARCHAR(20) NOT NULL,
+    cape_name VARCHAR(30) NOT NULL,
+    blob_key VARCHAR(255) NOT NULL
+);
+
+ALTER TABLE userstable ADD COLUMN IF NOT EXISTS active_cape_id INT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE userstable DROP COLUMN IF EXISTS active_cape_id;
+DROP TABLE IF EXISTS capestable;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE skinstable ADD COLUMN IF NOT EXISTS source_url VARCHAR(2048) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE skinstable DROP COLUMN IF EXISTS source_url;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS skinhistorytable (
+    history_id SERIAL PRIMARY KEY,
+    skin_id INT NOT NULL REFERENCES skinstable (skin_id) ON DELETE CASCADE,
+    previous_blob_key VARCHAR(255) NOT NULL DEFAULT '',
+    blob_key VARCHAR(255) NOT NULL,
+    skin_type VARCHAR(10) NOT NULL,
+    source_url VARCHAR(2048) NOT NULL DEFAULT '',
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Give back the texture references held by history entries
+UPDATE texturestable SET ref_count = texturestable.ref_count - refs.n
+    FROM (
+        SELECT key, COUNT(*) AS n FROM (
+            SELECT previous_blob_key AS key FROM skinhistorytable
+            UNION ALL
+            SELECT blob_key FROM skinhistorytable
+        ) AS keys WHERE key <> '' GROUP BY key
+    ) AS refs
+    WHERE texturestable.blob_key = refs.key;
+
+DROP TABLE IF EXISTS skinhistorytable;
+-- +goose StatementEnd
@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE skinstable ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS skinversionstable (
+    skin_id INT NOT NULL REFERENCES skinstable (skin_id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    skin_name VARCHAR(30) NOT NULL,
+    skin_type VARCHAR(10) NOT NULL,
+    skin_src VARCHAR(255) NOT NULL,
+    blob_key VARCHAR(255) NOT NULL DEFAULT '',
+    original_blob_key VARCHAR(255) NOT NULL DEFAULT '',
+    source_url VARCHAR(2048) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (skin_id, version)
+);
+
+-- The current state of every existing skin becomes its first version,
+-- which takes its own references on the textures
+WITH inserted AS (
+    INSERT INTO skinversionstable (skin_id, version, skin_name, skin_type, skin_src, blob_key, original_blob_key, source_url)
+    SELECT skin_id, version, skin_name, skin_type, skin_src, blob_key, original_blob_key, source_url FROM skinstable
+    ON CONFLICT DO NOTHING
+    RETURNING blob_key, original_blob_key
+)
+UPDATE texturestable SET ref_count = texturestable.ref_count + refs.n
+    FROM (
+        SELECT key, COUNT(*) AS n FROM (
+            SELECT blob_key AS key FROM inserted
+            UNION ALL
+            SELECT original_blob_key FROM inserted
+        ) AS keys WHERE key <> '' GROUP BY key
+    ) AS refs
+    WHERE texturestable.blob_key = refs.key;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE texturestable SET ref_count = texturestable.ref_count - refs.n
+    FROM (
+        SELECT key, COUNT(*) AS n FROM (
+            SELECT blob_key AS key FROM skinversionstable
+            UNION ALL
+            SELECT original_blob_key FROM skinversionstable
+        ) AS keys WHERE key <> '' GROUP BY key
+    ) AS refs
+    WHERE texturestable.blob_key = refs.key;
+
+DROP TABLE IF EXISTS skinversionstable;
+ALTER TABLE skinstable DROP COLUMN IF EXISTS version;
+-- +goose StatementEnd
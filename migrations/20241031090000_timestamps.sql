@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE userstable ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE userstable ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE skinstable ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE skinstable ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- Skins already have their versions timestamped, the best record of when they changed
+UPDATE skinstable SET created_at = versions.first, updated_at = versions.last
+    FROM (
+        SELECT skin_id, MIN(created_at) AS first, MAX(created_at) AS last FROM skinversionstable GROUP BY skin_id
+    ) AS versions
+    WHERE skinstable.skin_id = versions.skin_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE skinstable DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE skinstable DROP COLUMN IF EXISTS created_at;
+ALTER TABLE userstable DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE userstable DROP COLUMN IF EXISTS created_at;
+-- +goose StatementEnd
@@ -0,0 +1,63 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE skinstable ADD COLUMN IF NOT EXISTS owner_id INT REFERENCES userstable (user_id) ON DELETE CASCADE;
+ALTER TABLE capestable ADD COLUMN IF NOT EXISTS owner_id INT REFERENCES userstable (user_id) ON DELETE CASCADE;
+
+UPDATE skinstable SET owner_id = userstable.user_id
+    FROM userstable WHERE skinstable.owner_name = userstable.login AND skinstable.owner_id IS NULL;
+UPDATE capestable SET owner_id = userstable.user_id
+    FROM userstable WHERE capestable.owner_name = userstable.login AND capestable.owner_id IS NULL;
+
+-- Skins and capes whose owner no longer exists are dropped, along with the
+-- texture references they, their history and their versions held. SQL
+-- cannot reach the blob storage, unreferenced blobs are left where they are.
+UPDATE texturestable SET ref_count = texturestable.ref_count - refs.n
+    FROM (
+        SELECT key, COUNT(*) AS n FROM (
+            SELECT blob_key AS key FROM skinstable WHERE owner_id IS NULL
+            UNION ALL
+            SELECT original_blob_key FROM skinstable WHERE owner_id IS NULL
+            UNION ALL
+            SELECT blob_key FROM skinversionstable WHERE skin_id IN (SELECT skin_id FROM skinstable WHERE owner_id IS NULL)
+            UNION ALL
+            SELECT original_blob_key FROM skinversionstable WHERE skin_id IN (SELECT skin_id FROM skinstable WHERE owner_id IS NULL)
+            UNION ALL
+            SELECT blob_key FROM skinhistorytable WHERE skin_id IN (SELECT skin_id FROM skinstable WHERE owner_id IS NULL)
+            UNION ALL
+            SELECT previous_blob_key FROM skinhistorytable WHERE skin_id IN (SELECT skin_id FROM skinstable WHERE owner_id IS NULL)
+            UNION ALL
+            SELECT blob_key FROM capestable WHERE owner_id IS NULL
+        ) AS keys WHERE key <> '' GROUP BY key
+    ) AS refs
+    WHERE texturestable.blob_key = refs.key;
+
+DELETE FROM skinstable WHERE owner_id IS NULL;
+DELETE FROM capestable WHERE owner_id IS NULL;
+
+ALTER TABLE skinstable ALTER COLUMN owner_id SET NOT NULL;
+ALTER TABLE capestable ALTER COLUMN owner_id SET NOT NULL;
+
+ALTER TABLE skinstable DROP COLUMN IF EXISTS owner_name;
+ALTER TABLE capestable DROP COLUMN IF EXISTS owner_name;
+
+CREATE INDEX IF NOT EXISTS skinstable_owner_id_idx ON skinstable (owner_id);
+CREATE INDEX IF NOT EXISTS capestable_owner_id_idx ON capestable (owner_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE skinstable ADD COLUMN IF NOT EXISTS owner_name VARCHAR(20);
+ALTER TABLE capestable ADD COLUMN IF NOT EXISTS owner_name VARCHAR(20);
+
+UPDATE skinstable SET owner_name = userstable.login FROM userstable WHERE skinstable.owner_id = userstable.user_id;
+UPDATE capestable SET owner_name = userstable.login FROM userstable WHERE capestable.owner_id = userstable.user_id;
+
+ALTER TABLE skinstable ALTER COLUMN owner_name SET NOT NULL;
+ALTER TABLE capestable ALTER COLUMN owner_name SET NOT NULL;
+
+DROP INDEX IF EXISTS skinstable_owner_id_idx;
+DROP INDEX IF EXISTS capestable_owner_id_idx;
+
+ALTER TABLE skinstable DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE capestable DROP COLUMN IF EXISTS owner_id;
+-- +goose StatementEnd
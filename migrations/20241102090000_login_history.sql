@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS loginhistorytable (
+    history_id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES userstable (user_id) ON DELETE CASCADE,
+    login VARCHAR(20) NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS loginhistorytable_login_idx ON loginhistorytable (login);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS loginhistorytable;
+-- +goose StatementEnd
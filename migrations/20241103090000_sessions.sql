@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS sessionstable (
+    session_id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES userstable (user_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS refreshtokenstable (
+    token_hash CHAR(64) PRIMARY KEY,
+    session_id INT NOT NULL REFERENCES sessionstable (session_id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS refreshtokenstable_session_id_idx ON refreshtokenstable (session_id);
+
+-- Long-lived tokens are no longer stored, everyone logs in again
+ALTER TABLE userstable DROP COLUMN IF EXISTS token;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE userstable ADD COLUMN IF NOT EXISTS token VARCHAR(255) NOT NULL DEFAULT '';
+
+DROP TABLE IF EXISTS refreshtokenstable;
+DROP TABLE IF EXISTS sessionstable;
+-- +goose StatementEnd
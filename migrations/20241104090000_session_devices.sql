@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessionstable ADD COLUMN IF NOT EXISTS device_label VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE sessionstable ADD COLUMN IF NOT EXISTS user_agent VARCHAR(512) NOT NULL DEFAULT '';
+ALTER TABLE sessionstable ADD COLUMN IF NOT EXISTS ip VARCHAR(45) NOT NULL DEFAULT '';
+ALTER TABLE sessionstable ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS sessionstable_user_id_idx ON sessionstable (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS sessionstable_user_id_idx;
+
+ALTER TABLE sessionstable DROP COLUMN IF EXISTS last_seen_at;
+ALTER TABLE sessionstable DROP COLUMN IF EXISTS ip;
+ALTER TABLE sessionstable DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE sessionstable DROP COLUMN IF EXISTS device_label;
+-- +goose StatementEnd
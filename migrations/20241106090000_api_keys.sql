@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS apikeystable (
+    key_id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES userstable (user_id) ON DELETE CASCADE,
+    key_name VARCHAR(64) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    key_prefix VARCHAR(16) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS apikeystable_user_id_idx ON apikeystable (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS apikeystable;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+package models
+
+type Cape struct {
+	Name   string `form:"capename" binding:"required"`
+	Elytra bool   `form:"elytra"` // require the texture to carry an elytra
+}
+
+type CapeData struct {
+	Id      int
+	Name    string
+	Texture string // public URL of the stored texture
+	Hash    string `json:"-"`
+}
+
+type ActiveCape struct {
+	CapeId int `json:"capeid" binding:"required,min=1"`
+}
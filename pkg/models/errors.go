@@ -10,15 +10,67 @@ func (e *AppError) Error() string {
 }
 
 var (
-	ErrUserNotFound         = &AppError{"UserNotFound", "This user does not exist"}
-	ErrSkinNotFound         = &AppError{"SkinNotFound", "This skin does not exists"}
-	ErrAlrRegistered        = &AppError{"AlrRegistered", "This user is already registered"}
-	ErrInvalidToken         = &AppError{"InvalidToken", "Invalid token"}
-	ErrInvalidTokenFormat   = &AppError{"InvalidTokenFormat", "Invalid token format"}
-	ErrInvalidTokenClaims   = &AppError{"InvalidTokenClaims", "Invalid token claims"}
-	ErrTokenExpired         = &AppError{"TokenExpired", "Token has expired"}
-	ErrInvalidSigningMethod = &AppError{"InvalidSigningMethod", "Unexpected signing method"}
-	ErrTokenNotProvided     = &AppError{"TokenNotProvided", "Token is not provided"}
-	ErrInvalidSkinType      = &AppError{"InvalidSkinType", "Invalid skin type"}
-	ErrInvalidIdFormat      = &AppError{"InvalidIdFormat", "Invalid ID format"}
+	ErrUserNotFound          = &AppError{"UserNotFound", "This user does not exist"}
+	ErrSkinNotFound          = &AppError{"SkinNotFound", "This skin does not exists"}
+	ErrAlrRegistered         = &AppError{"AlrRegistered", "This user is already registered"}
+	ErrInvalidToken          = &AppError{"InvalidToken", "Invalid token"}
+	ErrInvalidTokenFormat    = &AppError{"InvalidTokenFormat", "Invalid token format"}
+	ErrInvalidTokenClaims    = &AppError{"InvalidTokenClaims", "Invalid token claims"}
+	ErrTokenExpired          = &AppError{"TokenExpired", "Token has expired"}
+	ErrInvalidSigningMethod  = &AppError{"InvalidSigningMethod", "Unexpected signing method"}
+	ErrTokenNotProvided      = &AppError{"TokenNotProvided", "Token is not provided"}
+	ErrInvalidSkinType       = &AppError{"InvalidSkinType", "Invalid skin type"}
+	ErrInvalidIdFormat       = &AppError{"InvalidIdFormat", "Invalid ID format"}
+	ErrInvalidIdValue        = &AppError{"InvalidIdValue", "ID must be greater than or equal to 1"}
+	ErrInvalidSkinTexture    = &AppError{"InvalidSkinTexture", "Skin texture must be a PNG image"}
+	ErrInvalidSkinSize       = &AppError{"InvalidSkinSize", "Skin texture must be 64x64 or 64x32 pixels"}
+	ErrInvalidSkinColor      = &AppError{"InvalidSkinColor", "Skin texture must be an RGBA image"}
+	ErrSkinTextureTooLarge   = &AppError{"SkinTextureTooLarge", "Skin texture file is too large"}
+	ErrSkinSourceMissing     = &AppError{"SkinSourceMissing", "Either a skin source or a skin texture file is required"}
+	ErrTextureNotFound       = &AppError{"TextureNotFound", "This texture does not exist"}
+	ErrBlobNotFound          = &AppError{"BlobNotFound", "Blob not found in storage"}
+	ErrInvalidBlobKey        = &AppError{"InvalidBlobKey", "Invalid blob key"}
+	ErrSkinTypeUndetected    = &AppError{"SkinTypeUndetected", "Skin type is required when there is no texture to detect it from"}
+	ErrSkinHasNoTexture      = &AppError{"SkinHasNoTexture", "This skin has no stored texture"}
+	ErrSkinNotLegacy         = &AppError{"SkinNotLegacy", "Skin texture is already in the 64x64 format"}
+	ErrMojangProfileNotFound = &AppError{"MojangProfileNotFound", "No Minecraft profile with this nickname"}
+	ErrMojangSkinNotFound    = &AppError{"MojangSkinNotFound", "This Minecraft profile has no custom skin"}
+	ErrInvalidAvatarSize     = &AppError{"InvalidAvatarSize", "Invalid avatar size"}
+	ErrInvalidRenderView     = &AppError{"InvalidRenderView", "View must be one of front, back or iso"}
+	ErrInvalidRenderScale    = &AppError{"InvalidRenderScale", "Invalid render scale"}
+	ErrSkinSourceUnreachable = &AppError{"SkinSourceUnreachable", "Could not download the skin texture from its source"}
+	ErrTooManyProfileNames   = &AppError{"TooManyProfileNames", "At most 10 names can be looked up at once"}
+	ErrActiveSkinNotSet      = &AppError{"ActiveSkinNotSet", "This user has no active skin"}
+	ErrCapeNotFound          = &AppError{"CapeNotFound", "This cape does not exist"}
+	ErrActiveCapeNotSet      = &AppError{"ActiveCapeNotSet", "This user has no active cape"}
+	ErrCapeFileMissing       = &AppError{"CapeFileMissing", "A cape texture file is required"}
+	ErrInvalidCapeTexture    = &AppError{"InvalidCapeTexture", "Cape texture must be a PNG image"}
+	ErrInvalidCapeSize       = &AppError{"InvalidCapeSize", "Cape texture must be 64x32 pixels"}
+	ErrInvalidCapeColor      = &AppError{"InvalidCapeColor", "Cape texture must be an RGBA image"}
+	ErrCapeElytraEmpty       = &AppError{"CapeElytraEmpty", "Cape texture has no elytra, its elytra region is fully transparent"}
+	ErrInvalidSkinSource     = &AppError{"InvalidSkinSource", "Skin source must be a URL or a Minecraft nickname"}
+	ErrSkinSourceForbidden   = &AppError{"SkinSourceForbidden", "Skin source points to an address that is not allowed"}
+	ErrSkinSourceNotPNG      = &AppError{"SkinSourceNotPNG", "Skin source did not return a PNG image"}
+	ErrSkinVersionNotFound   = &AppError{"SkinVersionNotFound", "This skin version does not exist"}
+	ErrInvalidVersion        = &AppError{"InvalidVersion", "Version must be a number greater than or equal to 1"}
+	ErrSkinVersionConflict   = &AppError{"SkinVersionConflict", "The skin was modified since it was read, fetch it again and retry"}
+	ErrEmptySkinName         = &AppError{"EmptySkinName", "Skin name must not be empty"}
+	ErrInvalidCursor         = &AppError{"InvalidCursor", "Invalid cursor, it must be the next_cursor of a page with the same sort and order"}
+	ErrLoginTaken            = &AppError{"LoginTaken", "This login is already taken"}
+	ErrSameLogin             = &AppError{"SameLogin", "New login is the same as the current one"}
+	ErrInvalidRefreshToken   = &AppError{"InvalidRefreshToken", "Invalid refresh token"}
+	ErrRefreshTokenExpired   = &AppError{"RefreshTokenExpired", "Refresh token has expired, log in again"}
+	ErrRefreshTokenReused    = &AppError{"RefreshTokenReused", "Refresh token was already used, the session has been revoked"}
+	ErrSessionNotFound       = &AppError{"SessionNotFound", "This session does not exist or has ended"}
+	ErrTokenRevoked          = &AppError{"TokenRevoked", "Token has been revoked, log in again"}
+	ErrApiKeyNotFound        = &AppError{"ApiKeyNotFound", "This API key does not exist"}
+	ErrInvalidApiKey         = &AppError{"InvalidApiKey", "Invalid API key"}
+	ErrInsufficientScope     = &AppError{"InsufficientScope", "This API key does not have the scope required for this request"}
+	ErrTooManyApiKeys        = &AppError{"TooManyApiKeys", "Too many API keys, revoke one before creating another"}
+	ErrInsufficientRole      = &AppError{"InsufficientRole", "Your role does not allow this request"}
+	ErrRoleNeedsSession      = &AppError{"RoleNeedsSession", "This request requires logging in, API keys are not accepted"}
+	ErrLastAdmin             = &AppError{"LastAdmin", "The last admin cannot be demoted"}
+	ErrBootstrapDisabled     = &AppError{"BootstrapDisabled", "Admin bootstrap is not enabled on this server"}
+	ErrInvalidBootstrapToken = &AppError{"InvalidBootstrapToken", "Invalid bootstrap token"}
+	ErrAdminExists           = &AppError{"AdminExists", "An admin already exists, ask them for a role"}
 )
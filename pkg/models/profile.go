@@ -0,0 +1,9 @@
+package models
+
+// Profile is a user as seen by Minecraft clients and servers
+type Profile struct {
+	Id   string // unsigned UUID
+	Name string
+	Skin *SkinData // skin worn in game, nil if the user has none
+	Cape *CapeData // cape worn in game, nil if the user has none
+}
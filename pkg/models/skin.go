@@ -1,14 +1,106 @@
 package models
 
+import "time"
+
+const (
+	SkinTypeClassic string = "Classic"
+	SkinTypeSlim    string = "Slim"
+)
+
 type Skin struct {
-	Name string `json:"skinname" binding:"required"`
-	Type string `json:"skintype" binding:"required"`
-	Src  string `json:"skinsrc" binding:"required"`
+	Name string `json:"skinname" form:"skinname" binding:"required"`
+	Type string `json:"skintype" form:"skintype"` // detected from the texture when omitted
+	Src  string `json:"skinsrc" form:"skinsrc"`
 }
 
 type SkinData struct {
-	Id   int
-	Name string
-	Type string
-	Src  string
+	Id      int
+	Name    string
+	Type    string
+	Src     string
+	Texture string `json:",omitempty"` // public URL of the stored texture, if any
+
+	// public URL of the texture as uploaded, when Texture was converted from it
+	OriginalTexture string `json:",omitempty"`
+
+	// URL the stored texture was downloaded from, empty for uploaded files
+	SourceURL string `json:",omitempty"`
+
+	Version int // number of the current version, bumped on every change
+
+	CreatedAt time.Time
+	UpdatedAt time.Time // last change of the name, type, source or texture
+
+	Hash string `json:"-"` // content hash of the stored texture
+}
+
+// SkinPatch is a partial update of a skin, absent fields are left unchanged
+type SkinPatch struct {
+	Name *string `json:"skinname"`
+	Type *string `json:"skintype"` // an empty type is detected again from a new texture
+	Src  *string `json:"skinsrc"`
+}
+
+const (
+	SkinSortCreated string = "created"
+	SkinSortName    string = "name"
+
+	SortAsc  string = "asc"
+	SortDesc string = "desc"
+)
+
+// SkinQuery selects one page of a user's skin collection
+type SkinQuery struct {
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor string `form:"cursor"` // next_cursor of the previous page
+	Type   string `form:"type" binding:"omitempty,oneof=Classic Slim"`
+	Name   string `form:"name" binding:"max=30"` // case-insensitive substring of the skin name
+	Sort   string `form:"sort" binding:"omitempty,oneof=created name"`
+	Order  string `form:"order" binding:"omitempty,oneof=asc desc"`
+}
+
+// SkinPage is one page of a user's skin collection
+type SkinPage struct {
+	Skins      []SkinData `json:"skins"`
+	NextCursor string     `json:"next_cursor,omitempty"` // empty on the last page
+	Total      int        `json:"total"`                 // number of skins matching the filters
+}
+
+// SkinTexture holds the PNG data stored with a skin
+type SkinTexture struct {
+	Data     []byte // canonical texture served to clients
+	Original []byte // texture as uploaded, when Data was converted from it
+
+	SourceURL string // URL the texture was downloaded from, empty for uploads
+}
+
+// SkinResult is returned when a skin is saved, with any non-fatal remarks about it
+type SkinResult struct {
+	SkinData
+	Warnings []string `json:",omitempty"`
+}
+
+// SkinHistoryEntry records a texture change of a nickname-sourced skin picked up by re-sync
+type SkinHistoryEntry struct {
+	Id              int
+	PreviousTexture string `json:",omitempty"` // public URL of the texture before the change
+	Texture         string // public URL of the texture after the change
+	Type            string
+	SourceURL       string `json:",omitempty"`
+	ChangedAt       time.Time
+}
+
+// SkinVersion is an immutable snapshot of a skin, taken every time it changes
+type SkinVersion struct {
+	Version         int
+	Name            string
+	Type            string
+	Src             string
+	Texture         string `json:",omitempty"`
+	OriginalTexture string `json:",omitempty"`
+	SourceURL       string `json:",omitempty"`
+	CreatedAt       time.Time
+
+	Hash         string `json:"-"` // blob keys of the textures
+	OriginalHash string `json:"-"`
 }
@@ -1,18 +1,138 @@
 package models
 
+import "time"
+
+const (
+	RoleUser      string = "user"
+	RoleModerator string = "moderator"
+	RoleAdmin     string = "admin"
+)
+
+// roles in increasing order of power, each has the powers of those below it
+var roleRanks = map[string]int{RoleUser: 1, RoleModerator: 2, RoleAdmin: 3}
+
+// HasRole reports whether role grants at least the powers of required
+func HasRole(role, required string) bool {
+	return roleRanks[required] > 0 && roleRanks[role] >= roleRanks[required]
+}
+
 type User struct {
 	Login    string `json:"login" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Device   string `json:"device" binding:"max=64"` // label of the session opened at login, e.g. "Laptop"
 }
 
 type UserData struct {
-	Id       int
-	Login    string
-	Password string
-	Token    string
+	Id         int
+	Login      string
+	Password   string
+	UUID       string // Minecraft profile id, without dashes
+	Role       string // one of RoleUser, RoleModerator or RoleAdmin
+	ActiveSkin *int   // id of the skin worn in game, nil if none is selected
+	ActiveCape *int   // id of the cape worn in game, nil if none is selected
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
 type UserInfo struct {
+	Login      string
+	Role       string
+	ActiveSkin *int
+	ActiveCape *int
+	Skins      []SkinData
+	Capes      []CapeData
+
+	PreviousLogins []LoginHistoryEntry `json:",omitempty"` // most recent first
+
+	CreatedAt time.Time
+	UpdatedAt time.Time // last change of the account, such as the active skin or cape
+}
+
+// TokenPair is issued at login and on every refresh
+type TokenPair struct {
+	AccessToken  string `json:"token"`         // short-lived JWT sent as Bearer token
+	RefreshToken string `json:"refresh_token"` // opaque, single use
+	ExpiresIn    int    `json:"expires_in"`    // access token lifetime in seconds
+}
+
+// SessionClient describes the device a session is used from
+type SessionClient struct {
+	Device    string
+	UserAgent string
+	IP        string
+}
+
+// Session is one device the user is logged in on
+type Session struct {
+	Id         int
+	Device     string
+	UserAgent  string
+	IP         string // address the session was last used from
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	Current    bool // the session of the request listing the sessions
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// API key scopes, NewApiKey's binding tag lists them again
+const (
+	ScopeSkinsRead  string = "skins:read"
+	ScopeSkinsWrite string = "skins:write"
+	ScopeCapesRead  string = "capes:read"
+	ScopeCapesWrite string = "capes:write"
+)
+
+// NewApiKey asks for a personal API key limited to some scopes
+type NewApiKey struct {
+	Name   string   `json:"name" binding:"required,max=64"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=skins:read skins:write capes:read capes:write"`
+}
+
+// ApiKey describes a personal API key, the key itself is only shown once
+type ApiKey struct {
+	Id         int
+	Name       string
+	Prefix     string // start of the key, to tell keys apart
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time // nil if the key was never used
+}
+
+// ApiKeyCreated is returned once, when a key is created
+type ApiKeyCreated struct {
+	ApiKey
+	Key string
+}
+
+// NewRole is the role an admin gives a user
+type NewRole struct {
+	Role string `json:"role" binding:"required,oneof=user moderator admin"`
+}
+
+// BootstrapRequest claims the first admin role with the server's bootstrap token
+type BootstrapRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type UserRole struct {
 	Login string
-	Skins []SkinData
+	Role  string
+}
+
+// NewLogin is the login a user renames themselves to
+type NewLogin struct {
+	Login string `json:"login" binding:"required"`
+}
+
+// LoginHistoryEntry is a login a user has given up
+type LoginHistoryEntry struct {
+	Login     string
+	ChangedAt time.Time
+}
+
+type ActiveSkin struct {
+	SkinId int `json:"skinid" binding:"required,min=1"`
 }